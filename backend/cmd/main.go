@@ -1,9 +1,12 @@
 package main
 
 import (
+	"consentis-api/internal/auth"
 	chainlistener "consentis-api/internal/chain-listener"
 	"consentis-api/internal/handlers"
+	"consentis-api/internal/pins"
 	"consentis-api/internal/repositories"
+	"consentis-api/internal/uploads"
 	"context"
 	"log"
 	"os"
@@ -27,6 +30,17 @@ func main() {
 	)
 	defer stop()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if err := migrateOnStartup(ctx); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
 	httpServer := handlers.NewServer(":8080")
 
 	go func() {
@@ -41,6 +55,12 @@ func main() {
 		chainlistener.StartEventListener(ctx)
 	}()
 
+	go uploads.StartWorkers(ctx)
+	go uploads.SweepOrphanPins(ctx)
+	go pins.StartMonitor(ctx)
+	go pins.StartReplicator(ctx)
+	go auth.SweepNonces(ctx)
+
 	<-ctx.Done()
 	log.Println("\nShutdown signal received...")
 
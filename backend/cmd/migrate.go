@@ -0,0 +1,111 @@
+package main
+
+import (
+	"consentis-api/internal/database"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate [up | down [N] | status]"
+
+// runMigrateCommand handles `migrate up`, `migrate down [N]` and
+// `migrate status`. up is the default.
+func runMigrateCommand(ctx context.Context, args []string) error {
+	pool, err := repositories.GetDB()
+	if err != nil {
+		return err
+	}
+	defer repositories.CloseDB()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		if len(args) > 1 {
+			return errors.New(migrateUsage)
+		}
+		applied, err := database.Migrate(ctx, pool)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s)", len(applied))
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 2 {
+			return errors.New(migrateUsage)
+		}
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of migrations to revert %q", args[1])
+			}
+		}
+		reverted, err := database.Rollback(ctx, pool, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reverted %d migration(s)", len(reverted))
+		return nil
+
+	case "status":
+		if len(args) > 1 {
+			return errors.New(migrateUsage)
+		}
+		statuses, err := database.Status(ctx, pool)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			if !s.Embedded {
+				state += " (unknown to this build)"
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+// migrateOnStartup applies pending migrations before the server starts unless
+// DB_AUTO_MIGRATE is false, for deployments that run `migrate` separately.
+func migrateOnStartup(ctx context.Context) error {
+	if value := os.Getenv("DB_AUTO_MIGRATE"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid DB_AUTO_MIGRATE: %w", err)
+		}
+		if !enabled {
+			log.Println("DB_AUTO_MIGRATE is false, skipping migrations")
+			return nil
+		}
+	}
+
+	pool, err := repositories.GetDB()
+	if err != nil {
+		return err
+	}
+
+	applied, err := database.Migrate(ctx, pool)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied %d migration(s)", len(applied))
+	}
+	return nil
+}
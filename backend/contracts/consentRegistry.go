@@ -31,7 +31,7 @@ var (
 
 // ConsentRegistryMetaData contains all meta data concerning the ConsentRegistry contract.
 var ConsentRegistryMetaData = &bind.MetaData{
-	ABI: "[{\"type\":\"function\",\"name\":\"checkAccess\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"grantConsent\",\"inputs\":[{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"hasConsent\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"revokeConsent\",\"inputs\":[{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"event\",\"name\":\"ConsentGranted\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"indexed\":false,\"internalType\":\"string\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ConsentRevoked\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"indexed\":false,\"internalType\":\"string\"}],\"anonymous\":false}]",
+	ABI: "[{\"type\":\"function\",\"name\":\"checkAccess\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"getRecordOwner\",\"inputs\":[{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[{\"name\":\"\",\"type\":\"address\",\"internalType\":\"address\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"grantConsent\",\"inputs\":[{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"hasConsent\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"isRecordOwner\",\"inputs\":[{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"},{\"name\":\"owner\",\"type\":\"address\",\"internalType\":\"address\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\",\"internalType\":\"bool\"}],\"stateMutability\":\"view\"},{\"type\":\"function\",\"name\":\"registerRecord\",\"inputs\":[{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"function\",\"name\":\"revokeConsent\",\"inputs\":[{\"name\":\"researcher\",\"type\":\"address\",\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"internalType\":\"string\"}],\"outputs\":[],\"stateMutability\":\"nonpayable\"},{\"type\":\"event\",\"name\":\"ConsentGranted\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"indexed\":false,\"internalType\":\"string\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"ConsentRevoked\",\"inputs\":[{\"name\":\"patient\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"researcher\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"},{\"name\":\"recordId\",\"type\":\"string\",\"indexed\":false,\"internalType\":\"string\"}],\"anonymous\":false},{\"type\":\"event\",\"name\":\"RecordRegistered\",\"inputs\":[{\"name\":\"recordId\",\"type\":\"string\",\"indexed\":true,\"internalType\":\"string\"},{\"name\":\"owner\",\"type\":\"address\",\"indexed\":true,\"internalType\":\"address\"}],\"anonymous\":false}]",
 }
 
 // ConsentRegistryABI is the input ABI used to generate the binding from.
@@ -211,6 +211,37 @@ func (_ConsentRegistry *ConsentRegistryCallerSession) CheckAccess(patient common
 	return _ConsentRegistry.Contract.CheckAccess(&_ConsentRegistry.CallOpts, patient, researcher, recordId)
 }
 
+// GetRecordOwner is a free data retrieval call binding the contract method 0x26122c06.
+//
+// Solidity: function getRecordOwner(string recordId) view returns(address)
+func (_ConsentRegistry *ConsentRegistryCaller) GetRecordOwner(opts *bind.CallOpts, recordId string) (common.Address, error) {
+	var out []interface{}
+	err := _ConsentRegistry.contract.Call(opts, &out, "getRecordOwner", recordId)
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// GetRecordOwner is a free data retrieval call binding the contract method 0x26122c06.
+//
+// Solidity: function getRecordOwner(string recordId) view returns(address)
+func (_ConsentRegistry *ConsentRegistrySession) GetRecordOwner(recordId string) (common.Address, error) {
+	return _ConsentRegistry.Contract.GetRecordOwner(&_ConsentRegistry.CallOpts, recordId)
+}
+
+// GetRecordOwner is a free data retrieval call binding the contract method 0x26122c06.
+//
+// Solidity: function getRecordOwner(string recordId) view returns(address)
+func (_ConsentRegistry *ConsentRegistryCallerSession) GetRecordOwner(recordId string) (common.Address, error) {
+	return _ConsentRegistry.Contract.GetRecordOwner(&_ConsentRegistry.CallOpts, recordId)
+}
+
 // HasConsent is a free data retrieval call binding the contract method 0xdbf7a00c.
 //
 // Solidity: function hasConsent(address patient, address researcher, string recordId) view returns(bool)
@@ -242,6 +273,37 @@ func (_ConsentRegistry *ConsentRegistryCallerSession) HasConsent(patient common.
 	return _ConsentRegistry.Contract.HasConsent(&_ConsentRegistry.CallOpts, patient, researcher, recordId)
 }
 
+// IsRecordOwner is a free data retrieval call binding the contract method 0xe16ade45.
+//
+// Solidity: function isRecordOwner(string recordId, address owner) view returns(bool)
+func (_ConsentRegistry *ConsentRegistryCaller) IsRecordOwner(opts *bind.CallOpts, recordId string, owner common.Address) (bool, error) {
+	var out []interface{}
+	err := _ConsentRegistry.contract.Call(opts, &out, "isRecordOwner", recordId, owner)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// IsRecordOwner is a free data retrieval call binding the contract method 0xe16ade45.
+//
+// Solidity: function isRecordOwner(string recordId, address owner) view returns(bool)
+func (_ConsentRegistry *ConsentRegistrySession) IsRecordOwner(recordId string, owner common.Address) (bool, error) {
+	return _ConsentRegistry.Contract.IsRecordOwner(&_ConsentRegistry.CallOpts, recordId, owner)
+}
+
+// IsRecordOwner is a free data retrieval call binding the contract method 0xe16ade45.
+//
+// Solidity: function isRecordOwner(string recordId, address owner) view returns(bool)
+func (_ConsentRegistry *ConsentRegistryCallerSession) IsRecordOwner(recordId string, owner common.Address) (bool, error) {
+	return _ConsentRegistry.Contract.IsRecordOwner(&_ConsentRegistry.CallOpts, recordId, owner)
+}
+
 // GrantConsent is a paid mutator transaction binding the contract method 0x88973288.
 //
 // Solidity: function grantConsent(address researcher, string recordId) returns()
@@ -263,6 +325,27 @@ func (_ConsentRegistry *ConsentRegistryTransactorSession) GrantConsent(researche
 	return _ConsentRegistry.Contract.GrantConsent(&_ConsentRegistry.TransactOpts, researcher, recordId)
 }
 
+// RegisterRecord is a paid mutator transaction binding the contract method 0xfec9e61f.
+//
+// Solidity: function registerRecord(string recordId) returns()
+func (_ConsentRegistry *ConsentRegistryTransactor) RegisterRecord(opts *bind.TransactOpts, recordId string) (*types.Transaction, error) {
+	return _ConsentRegistry.contract.Transact(opts, "registerRecord", recordId)
+}
+
+// RegisterRecord is a paid mutator transaction binding the contract method 0xfec9e61f.
+//
+// Solidity: function registerRecord(string recordId) returns()
+func (_ConsentRegistry *ConsentRegistrySession) RegisterRecord(recordId string) (*types.Transaction, error) {
+	return _ConsentRegistry.Contract.RegisterRecord(&_ConsentRegistry.TransactOpts, recordId)
+}
+
+// RegisterRecord is a paid mutator transaction binding the contract method 0xfec9e61f.
+//
+// Solidity: function registerRecord(string recordId) returns()
+func (_ConsentRegistry *ConsentRegistryTransactorSession) RegisterRecord(recordId string) (*types.Transaction, error) {
+	return _ConsentRegistry.Contract.RegisterRecord(&_ConsentRegistry.TransactOpts, recordId)
+}
+
 // RevokeConsent is a paid mutator transaction binding the contract method 0xbd41ad8b.
 //
 // Solidity: function revokeConsent(address researcher, string recordId) returns()
@@ -591,3 +674,156 @@ func (_ConsentRegistry *ConsentRegistryFilterer) ParseConsentRevoked(log types.L
 	event.Raw = log
 	return event, nil
 }
+
+// ConsentRegistryRecordRegisteredIterator is returned from FilterRecordRegistered and is used to iterate over the raw logs and unpacked data for RecordRegistered events raised by the ConsentRegistry contract.
+type ConsentRegistryRecordRegisteredIterator struct {
+	Event *ConsentRegistryRecordRegistered // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *ConsentRegistryRecordRegisteredIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(ConsentRegistryRecordRegistered)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(ConsentRegistryRecordRegistered)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *ConsentRegistryRecordRegisteredIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *ConsentRegistryRecordRegisteredIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// ConsentRegistryRecordRegistered represents a RecordRegistered event raised by the ConsentRegistry contract.
+type ConsentRegistryRecordRegistered struct {
+	RecordId common.Hash
+	Owner    common.Address
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterRecordRegistered is a free log retrieval operation binding the contract event 0x5eea9e0ee0b5b054cd1cd9d1214e540f54f048e935489e748b8d3c74ac69a7c9.
+//
+// Solidity: event RecordRegistered(string indexed recordId, address indexed owner)
+func (_ConsentRegistry *ConsentRegistryFilterer) FilterRecordRegistered(opts *bind.FilterOpts, recordId []string, owner []common.Address) (*ConsentRegistryRecordRegisteredIterator, error) {
+
+	var recordIdRule []interface{}
+	for _, recordIdItem := range recordId {
+		recordIdRule = append(recordIdRule, recordIdItem)
+	}
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+
+	logs, sub, err := _ConsentRegistry.contract.FilterLogs(opts, "RecordRegistered", recordIdRule, ownerRule)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsentRegistryRecordRegisteredIterator{contract: _ConsentRegistry.contract, event: "RecordRegistered", logs: logs, sub: sub}, nil
+}
+
+// WatchRecordRegistered is a free log subscription operation binding the contract event 0x5eea9e0ee0b5b054cd1cd9d1214e540f54f048e935489e748b8d3c74ac69a7c9.
+//
+// Solidity: event RecordRegistered(string indexed recordId, address indexed owner)
+func (_ConsentRegistry *ConsentRegistryFilterer) WatchRecordRegistered(opts *bind.WatchOpts, sink chan<- *ConsentRegistryRecordRegistered, recordId []string, owner []common.Address) (event.Subscription, error) {
+
+	var recordIdRule []interface{}
+	for _, recordIdItem := range recordId {
+		recordIdRule = append(recordIdRule, recordIdItem)
+	}
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+
+	logs, sub, err := _ConsentRegistry.contract.WatchLogs(opts, "RecordRegistered", recordIdRule, ownerRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(ConsentRegistryRecordRegistered)
+				if err := _ConsentRegistry.contract.UnpackLog(event, "RecordRegistered", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseRecordRegistered is a log parse operation binding the contract event 0x5eea9e0ee0b5b054cd1cd9d1214e540f54f048e935489e748b8d3c74ac69a7c9.
+//
+// Solidity: event RecordRegistered(string indexed recordId, address indexed owner)
+func (_ConsentRegistry *ConsentRegistryFilterer) ParseRecordRegistered(log types.Log) (*ConsentRegistryRecordRegistered, error) {
+	event := new(ConsentRegistryRecordRegistered)
+	if err := _ConsentRegistry.contract.UnpackLog(event, "RecordRegistered", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
@@ -0,0 +1,247 @@
+package access
+
+import (
+	consentRegistry "consentis-api/contracts"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	DefaultCacheTTL = 30 * time.Second
+	callTimeout     = 10 * time.Second
+
+	// maxCacheEntries bounds the answer cache; records and researchers are
+	// caller supplied, so it would otherwise grow with every new pair.
+	maxCacheEntries = 10000
+
+	// JSON-RPC error code of a reverted eth_call
+	revertErrorCode = 3
+)
+
+// accessChecker is the part of the generated ConsentRegistryCaller used here.
+type accessChecker interface {
+	CheckAccess(opts *bind.CallOpts, patient common.Address, researcher common.Address, recordId string) (bool, error)
+}
+
+type cacheKey struct {
+	patient    string
+	researcher string
+	recordID   string
+}
+
+type cacheEntry struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// Verifier answers whether the ConsentRegistry currently lets a wallet read a
+// record. Answers are cached for a short time and dropped as soon as the
+// indexer sees a consent or registration event for the record.
+type Verifier struct {
+	mu      sync.Mutex
+	checker accessChecker
+	close   func()
+	connect func(ctx context.Context) (accessChecker, func(), error)
+	ttl     time.Duration
+	entries map[cacheKey]cacheEntry
+	pruneAt time.Time // when expired entries are next swept
+
+	// generation is bumped by Invalidate, so an answer fetched before an
+	// invalidation is not cached after it.
+	generation uint64
+}
+
+var (
+	verifierInstance *Verifier
+	verifierOnce     sync.Once
+)
+
+// GetVerifier returns the shared verifier. It connects to ETH_CLIENT_ADDRESS
+// on first use, so the cache can be invalidated without a node connection.
+func GetVerifier() *Verifier {
+	verifierOnce.Do(func() {
+		verifierInstance = &Verifier{
+			connect: dialContract,
+			ttl:     getCacheTTL(),
+			entries: make(map[cacheKey]cacheEntry),
+		}
+	})
+	return verifierInstance
+}
+
+func NewVerifier(checker accessChecker, ttl time.Duration) *Verifier {
+	return &Verifier{
+		checker: checker,
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+	}
+}
+
+// CheckAccess calls ConsentRegistry.checkAccess. A call the contract reverts,
+// such as one naming the wrong owner, counts as access denied.
+func (v *Verifier) CheckAccess(ctx context.Context, patient string, researcher string, recordID string) (bool, error) {
+	key := cacheKey{
+		patient:    strings.ToLower(patient),
+		researcher: strings.ToLower(researcher),
+		recordID:   recordID,
+	}
+
+	v.mu.Lock()
+	entry, ok := v.entries[key]
+	generation := v.generation
+	v.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.allowed, nil
+	}
+
+	checker, err := v.getChecker(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	allowed, err := checker.CheckAccess(&bind.CallOpts{Context: callCtx},
+		common.HexToAddress(patient), common.HexToAddress(researcher), recordID)
+	if err != nil {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != revertErrorCode {
+			v.disconnect()
+			return false, fmt.Errorf("checkAccess: %w", err)
+		}
+		allowed = false
+	}
+
+	v.store(key, cacheEntry{allowed: allowed, expiresAt: time.Now().Add(v.ttl)}, generation)
+
+	return allowed, nil
+}
+
+// store caches an answer fetched at the given generation, unless the cache
+// was invalidated since. Expired entries are swept at most once per TTL, or
+// when the cache is full; a cache still full of live answers then drops an
+// arbitrary one.
+func (v *Verifier) store(key cacheKey, entry cacheEntry, generation uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.generation != generation {
+		return
+	}
+
+	now := time.Now()
+	if _, ok := v.entries[key]; !ok && (len(v.entries) >= maxCacheEntries || !now.Before(v.pruneAt)) {
+		for k, e := range v.entries {
+			if !now.Before(e.expiresAt) {
+				delete(v.entries, k)
+			}
+		}
+		v.pruneAt = now.Add(v.ttl)
+
+		for k := range v.entries {
+			if len(v.entries) < maxCacheEntries {
+				break
+			}
+			delete(v.entries, k)
+		}
+	}
+	v.entries[key] = entry
+}
+
+// Invalidate drops the cached answers for a record. An empty researcher drops
+// them for every researcher, as when the record's owner changes.
+func (v *Verifier) Invalidate(recordID string, researcher string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.generation++
+	for key := range v.entries {
+		if key.recordID != recordID {
+			continue
+		}
+		if researcher != "" && key.researcher != strings.ToLower(researcher) {
+			continue
+		}
+		delete(v.entries, key)
+	}
+}
+
+func (v *Verifier) getChecker(ctx context.Context) (accessChecker, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.checker != nil {
+		return v.checker, nil
+	}
+
+	checker, closeFn, err := v.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.checker = checker
+	v.close = closeFn
+	return checker, nil
+}
+
+// disconnect drops a dialed connection after a failed call, so the next check
+// dials again instead of reusing a dead websocket.
+func (v *Verifier) disconnect() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.close == nil {
+		return
+	}
+	v.close()
+	v.close = nil
+	v.checker = nil
+}
+
+func dialContract(ctx context.Context) (accessChecker, func(), error) {
+	contractAddress := os.Getenv("CONTRACT_ADDRESS")
+	ethClientAddress := os.Getenv("ETH_CLIENT_ADDRESS")
+	if contractAddress == "" || ethClientAddress == "" {
+		return nil, nil, errors.New("CONTRACT_ADDRESS and ETH_CLIENT_ADDRESS must be set")
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(dialCtx, ethClientAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial eth client: %w", err)
+	}
+
+	caller, err := consentRegistry.NewConsentRegistryCaller(common.HexToAddress(contractAddress), client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return caller, client.Close, nil
+}
+
+func getCacheTTL() time.Duration {
+	value := os.Getenv("ACCESS_CACHE_TTL")
+	if value == "" {
+		return DefaultCacheTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Printf("Invalid ACCESS_CACHE_TTL %q, using %s", value, DefaultCacheTTL)
+		return DefaultCacheTTL
+	}
+	return ttl
+}
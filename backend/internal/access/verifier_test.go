@@ -0,0 +1,185 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	patient    = "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	researcher = "0xAbCdEf0000000000000000000000000000000001"
+	recordID   = "550e8400-e29b-41d4-a716-446655440000"
+)
+
+type fakeChecker struct {
+	calls   int
+	allowed bool
+	err     error
+	onCall  func()
+}
+
+func (f *fakeChecker) CheckAccess(opts *bind.CallOpts, patient common.Address, researcher common.Address, recordId string) (bool, error) {
+	f.calls++
+	if f.onCall != nil {
+		f.onCall()
+	}
+	return f.allowed, f.err
+}
+
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted: Invalid record owner" }
+func (revertError) ErrorCode() int { return revertErrorCode }
+
+func TestVerifier_CachesAnswers(t *testing.T) {
+	checker := &fakeChecker{allowed: true}
+	verifier := NewVerifier(checker, time.Minute)
+
+	for range 3 {
+		allowed, err := verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !allowed {
+			t.Error("expected access to be allowed")
+		}
+	}
+
+	if checker.calls != 1 {
+		t.Errorf("expected 1 contract call, got %d", checker.calls)
+	}
+}
+
+func TestVerifier_Invalidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordID   string
+		researcher string
+		wantCalls  int
+	}{
+		{"Same researcher", recordID, researcher, 2},
+		{"Every researcher", recordID, "", 2},
+		{"Checksum casing", recordID, "0xabcdef0000000000000000000000000000000001", 2},
+		{"Other researcher", recordID, patient, 1},
+		{"Other record", "other-record", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &fakeChecker{allowed: true}
+			verifier := NewVerifier(checker, time.Minute)
+
+			verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+			verifier.Invalidate(tt.recordID, tt.researcher)
+			verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+
+			if checker.calls != tt.wantCalls {
+				t.Errorf("expected %d contract calls, got %d", tt.wantCalls, checker.calls)
+			}
+		})
+	}
+}
+
+func TestVerifier_InvalidateDuringCheck(t *testing.T) {
+	checker := &fakeChecker{allowed: true}
+	verifier := NewVerifier(checker, time.Minute)
+	checker.onCall = func() {
+		checker.onCall = nil
+		verifier.Invalidate(recordID, researcher)
+	}
+
+	verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+	verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+
+	if checker.calls != 2 {
+		t.Errorf("expected an answer fetched before an invalidation not to be cached, got %d calls", checker.calls)
+	}
+}
+
+func TestVerifier_Expiry(t *testing.T) {
+	checker := &fakeChecker{allowed: false}
+	verifier := NewVerifier(checker, 0)
+
+	verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+	verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+
+	if checker.calls != 2 {
+		t.Errorf("expected an expired answer to be checked again, got %d calls", checker.calls)
+	}
+}
+
+func TestVerifier_PrunesEntries(t *testing.T) {
+	checker := &fakeChecker{allowed: true}
+	verifier := NewVerifier(checker, time.Millisecond)
+
+	verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+	time.Sleep(5 * time.Millisecond)
+	verifier.CheckAccess(context.Background(), patient, researcher, "other-record")
+
+	if len(verifier.entries) != 1 {
+		t.Errorf("expected the expired entry to be pruned, got %d entries", len(verifier.entries))
+	}
+
+	verifier = NewVerifier(checker, time.Hour)
+	for i := range maxCacheEntries + 10 {
+		verifier.CheckAccess(context.Background(), patient, researcher, strconv.Itoa(i))
+	}
+	if len(verifier.entries) != maxCacheEntries {
+		t.Errorf("expected %d entries, got %d", maxCacheEntries, len(verifier.entries))
+	}
+}
+
+func TestVerifier_Errors(t *testing.T) {
+	t.Run("revert denies access", func(t *testing.T) {
+		verifier := NewVerifier(&fakeChecker{err: revertError{}}, time.Minute)
+
+		allowed, err := verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if allowed {
+			t.Error("expected access to be denied")
+		}
+	})
+
+	t.Run("node error is returned and not cached", func(t *testing.T) {
+		checker := &fakeChecker{err: errors.New("connection refused")}
+		verifier := NewVerifier(checker, time.Minute)
+
+		if _, err := verifier.CheckAccess(context.Background(), patient, researcher, recordID); err == nil {
+			t.Fatal("expected an error")
+		}
+		verifier.CheckAccess(context.Background(), patient, researcher, recordID)
+
+		if checker.calls != 2 {
+			t.Errorf("expected the failed check to be retried, got %d calls", checker.calls)
+		}
+	})
+}
+
+func TestGetCacheTTL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultCacheTTL},
+		{"5s", 5 * time.Second},
+		{"0s", 0},
+		{"often", DefaultCacheTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("ACCESS_CACHE_TTL", tt.value)
+			if got := getCacheTTL(); got != tt.want {
+				t.Errorf("getCacheTTL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
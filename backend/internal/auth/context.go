@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+type contextKey struct{}
+
+var walletKey = contextKey{}
+
+func WithWallet(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, walletKey, address)
+}
+
+func WalletFromContext(ctx context.Context) (string, bool) {
+	address, ok := ctx.Value(walletKey).(string)
+	return address, ok && address != ""
+}
+
+// SameAddress compares two Ethereum addresses ignoring EIP-55 checksum casing.
+func SameAddress(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SessionTTL = 24 * time.Hour
+
+var (
+	ErrInvalidToken = errors.New("invalid session token")
+	ErrExpiredToken = errors.New("session token has expired")
+)
+
+type Session struct {
+	Address   string `json:"addr"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type SessionManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+var (
+	sessionInstance *SessionManager
+	sessionOnce     sync.Once
+	nonceInstance   *NonceStore
+	nonceOnce       sync.Once
+)
+
+func GetSessionManager() *SessionManager {
+	sessionOnce.Do(func() {
+		secret := []byte(os.Getenv("AUTH_SESSION_SECRET"))
+		if len(secret) == 0 {
+			log.Println("AUTH_SESSION_SECRET not set, using an ephemeral secret. Sessions will not survive restarts.")
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				log.Fatal("generate session secret:", err)
+			}
+		}
+		sessionInstance = NewSessionManager(secret, SessionTTL)
+	})
+	return sessionInstance
+}
+
+func GetNonceStore() *NonceStore {
+	nonceOnce.Do(func() {
+		nonceInstance = NewNonceStore(NonceTTL)
+	})
+	return nonceInstance
+}
+
+func NewSessionManager(secret []byte, ttl time.Duration) *SessionManager {
+	return &SessionManager{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue creates a signed token of the form base64(payload).base64(hmac).
+func (m *SessionManager) Issue(address string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(m.ttl)
+	payload, err := json.Marshal(Session{
+		Address:   address,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + m.sign(encoded), expiresAt, nil
+}
+
+func (m *SessionManager) Verify(token string) (*Session, error) {
+	encoded, sig, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(m.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var session Session
+	if err := json.Unmarshal(payload, &session); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= session.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &session, nil
+}
+
+func (m *SessionManager) sign(encoded string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionManager(t *testing.T) {
+	manager := NewSessionManager([]byte("test-secret"), time.Hour)
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+
+	token, _, err := manager.Issue(address)
+	if err != nil {
+		t.Fatalf("Issue() unexpected error: %v", err)
+	}
+
+	session, err := manager.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if session.Address != address {
+		t.Errorf("Address mismatch: got %v, want %v", session.Address, address)
+	}
+
+	other := NewSessionManager([]byte("other-secret"), time.Hour)
+	if _, err := other.Verify(token); err != ErrInvalidToken {
+		t.Errorf("Expected ErrInvalidToken for foreign secret, got %v", err)
+	}
+
+	expired := NewSessionManager([]byte("test-secret"), -time.Minute)
+	expiredToken, _, _ := expired.Issue(address)
+	if _, err := manager.Verify(expiredToken); err != ErrExpiredToken {
+		t.Errorf("Expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	store := NewNonceStore(time.Minute)
+
+	nonce, err := store.Issue("192.0.2.1")
+	if err != nil {
+		t.Fatalf("Issue() unexpected error: %v", err)
+	}
+	if len(nonce) < 8 {
+		t.Errorf("Expected nonce of at least 8 characters, got %q", nonce)
+	}
+
+	if !store.Consume(nonce) {
+		t.Error("Expected first Consume() to succeed")
+	}
+	if store.Consume(nonce) {
+		t.Error("Expected second Consume() to fail")
+	}
+	if store.Consume("unknown") {
+		t.Error("Expected Consume() of unknown nonce to fail")
+	}
+}
+
+func TestNonceStore_RateLimitsClients(t *testing.T) {
+	store := NewNonceStore(time.Minute)
+	store.limit = 2
+
+	for range 2 {
+		if _, err := store.Issue("192.0.2.1"); err != nil {
+			t.Fatalf("Issue() unexpected error: %v", err)
+		}
+	}
+	if _, err := store.Issue("192.0.2.1"); err != ErrNonceRateLimited {
+		t.Errorf("Expected ErrNonceRateLimited, got %v", err)
+	}
+	if _, err := store.Issue("192.0.2.2"); err != nil {
+		t.Errorf("Expected another client to get a nonce, got %v", err)
+	}
+
+	store.clients["192.0.2.1"] = clientWindow{count: 2, resetAt: time.Now()}
+	store.Sweep()
+	if _, ok := store.clients["192.0.2.1"]; ok {
+		t.Error("Expected the finished window to be swept")
+	}
+	if _, err := store.Issue("192.0.2.1"); err != nil {
+		t.Errorf("Expected a new window, got %v", err)
+	}
+}
+
+func TestNonceStore_Capacity(t *testing.T) {
+	store := NewNonceStore(time.Minute)
+	store.max = 2
+
+	store.Issue("192.0.2.1")
+	store.Issue("192.0.2.2")
+	if _, err := store.Issue("192.0.2.3"); err != ErrNonceStoreFull {
+		t.Errorf("Expected ErrNonceStoreFull, got %v", err)
+	}
+}
+
+func TestNonceStore_Sweep(t *testing.T) {
+	store := NewNonceStore(-time.Minute)
+	store.Issue("192.0.2.1")
+
+	store.Sweep()
+	if len(store.nonces) != 0 {
+		t.Errorf("Expected expired nonces to be swept, got %d", len(store.nonces))
+	}
+}
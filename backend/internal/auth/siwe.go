@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SiweMessage is a parsed EIP-4361 (Sign-In with Ethereum) message.
+type SiweMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+func ParseSiweMessage(raw string) (*SiweMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, errors.New("siwe message is too short")
+	}
+
+	if !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, errors.New("siwe message has an invalid header")
+	}
+
+	msg := &SiweMessage{
+		Domain:  strings.TrimSuffix(lines[0], siweHeaderSuffix),
+		Address: strings.TrimSpace(lines[1]),
+	}
+
+	if msg.Domain == "" {
+		return nil, errors.New("siwe message is missing the domain")
+	}
+	if !common.IsHexAddress(msg.Address) {
+		return nil, errors.New("siwe message has an invalid address")
+	}
+
+	var statement []string
+	inResources := false
+	for _, line := range lines[2:] {
+		if inResources {
+			if strings.HasPrefix(line, "- ") {
+				msg.Resources = append(msg.Resources, strings.TrimPrefix(line, "- "))
+				continue
+			}
+			inResources = false
+		}
+
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			if line == "Resources:" {
+				inResources = true
+			} else if strings.TrimSpace(line) != "" && msg.URI == "" {
+				statement = append(statement, line)
+			}
+			continue
+		}
+
+		var err error
+		switch key {
+		case "URI":
+			msg.URI = value
+		case "Version":
+			msg.Version = value
+		case "Chain ID":
+			msg.ChainID, err = strconv.ParseInt(value, 10, 64)
+		case "Nonce":
+			msg.Nonce = value
+		case "Issued At":
+			msg.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			msg.ExpirationTime, err = parseOptionalTime(value)
+		case "Not Before":
+			msg.NotBefore, err = parseOptionalTime(value)
+		case "Request ID":
+			msg.RequestID = value
+		default:
+			if msg.URI == "" {
+				statement = append(statement, line)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("siwe message has an invalid %s: %w", key, err)
+		}
+	}
+	msg.Statement = strings.Join(statement, "\n")
+
+	if msg.URI == "" {
+		return nil, errors.New("siwe message is missing the URI")
+	}
+	if msg.Version != "1" {
+		return nil, errors.New("siwe message has an unsupported version")
+	}
+	if msg.Nonce == "" {
+		return nil, errors.New("siwe message is missing the nonce")
+	}
+	if msg.IssuedAt.IsZero() {
+		return nil, errors.New("siwe message is missing the issued at time")
+	}
+
+	return msg, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks the message is addressed to the expected domain and is
+// within its validity window.
+func (m *SiweMessage) Validate(domain string, now time.Time) error {
+	if !strings.EqualFold(m.Domain, domain) {
+		return fmt.Errorf("siwe domain %q does not match %q", m.Domain, domain)
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return errors.New("siwe message has expired")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return errors.New("siwe message is not yet valid")
+	}
+	return nil
+}
+
+// RecoverSigner returns the address that produced an EIP-191 personal_sign
+// signature over the raw message.
+func RecoverSigner(raw string, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("invalid signature length")
+	}
+
+	// Wallets return V as 27/28, go-ethereum expects 0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(raw)), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("recover public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const validMessage = `localhost:3000 wants you to sign in with your Ethereum account:
+0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2
+
+Sign in to Consentis.
+
+URI: http://localhost:3000
+Version: 1
+Chain ID: 11155111
+Nonce: 32891756
+Issued At: 2025-01-01T00:00:00Z
+Expiration Time: 2025-01-02T00:00:00Z
+Resources:
+- ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq`
+
+func TestParseSiweMessage(t *testing.T) {
+	msg, err := ParseSiweMessage(validMessage)
+	if err != nil {
+		t.Fatalf("ParseSiweMessage() unexpected error: %v", err)
+	}
+
+	if msg.Domain != "localhost:3000" {
+		t.Errorf("Domain mismatch: got %v", msg.Domain)
+	}
+	if msg.Address != "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2" {
+		t.Errorf("Address mismatch: got %v", msg.Address)
+	}
+	if msg.Statement != "Sign in to Consentis." {
+		t.Errorf("Statement mismatch: got %v", msg.Statement)
+	}
+	if msg.ChainID != 11155111 {
+		t.Errorf("ChainID mismatch: got %v", msg.ChainID)
+	}
+	if msg.Nonce != "32891756" {
+		t.Errorf("Nonce mismatch: got %v", msg.Nonce)
+	}
+	if msg.ExpirationTime == nil {
+		t.Error("Expected ExpirationTime to be set")
+	}
+	if len(msg.Resources) != 1 {
+		t.Errorf("Expected 1 resource, got %d", len(msg.Resources))
+	}
+}
+
+func TestParseSiweMessage_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		errMsg  string
+	}{
+		{"Empty", "", "too short"},
+		{"Bad header", strings.Replace(validMessage, "wants you to sign in", "would like", 1), "invalid header"},
+		{"Bad address", strings.Replace(validMessage, "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2", "0x123", 1), "invalid address"},
+		{"Missing nonce", strings.Replace(validMessage, "Nonce: 32891756\n", "", 1), "missing the nonce"},
+		{"Wrong version", strings.Replace(validMessage, "Version: 1", "Version: 2", 1), "unsupported version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSiweMessage(tt.message)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error to contain %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestSiweMessageValidate(t *testing.T) {
+	msg, err := ParseSiweMessage(validMessage)
+	if err != nil {
+		t.Fatalf("ParseSiweMessage() unexpected error: %v", err)
+	}
+
+	if err := msg.Validate("localhost:3000", msg.IssuedAt.Add(time.Hour)); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	if err := msg.Validate("example.com", msg.IssuedAt.Add(time.Hour)); err == nil {
+		t.Error("Expected domain mismatch error")
+	}
+	if err := msg.Validate("localhost:3000", msg.ExpirationTime.Add(time.Second)); err == nil {
+		t.Error("Expected expiration error")
+	}
+}
+
+func TestRecoverSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	message := "hello consentis"
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	signer, err := RecoverSigner(message, hexutil.Encode(sig))
+	if err != nil {
+		t.Fatalf("RecoverSigner() unexpected error: %v", err)
+	}
+	if signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Signer mismatch: got %v", signer.Hex())
+	}
+
+	if _, err := RecoverSigner(message, "0x1234"); err == nil {
+		t.Error("Expected error for short signature")
+	}
+}
@@ -0,0 +1,172 @@
+package chainlistener
+
+import (
+	"cmp"
+	"consentis-api/internal/repositories"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const defaultBackfillChunkSize = 2000
+
+func cursorName(contractAddr common.Address) string {
+	return strings.ToLower(contractAddr.Hex())
+}
+
+// resolveStartBlock returns the first block that still has to be processed.
+// On first run the cursor is seeded from CONTRACT_DEPLOYMENT_BLOCK, falling
+// back to the current head when it is not configured.
+func resolveStartBlock(name string, head uint64) (uint64, error) {
+	lastBlock, found, err := repositories.GetIndexerCursor(name)
+	if err != nil {
+		return 0, err
+	}
+
+	deploymentBlock, configured, err := getDeploymentBlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if found {
+		return replayRegistrations(name, lastBlock+1, deploymentBlock, configured)
+	}
+
+	if err := repositories.SaveIndexerCursor(name+registrationMarker, 0); err != nil {
+		return 0, err
+	}
+	if configured {
+		return deploymentBlock, nil
+	}
+
+	log.Printf("CONTRACT_DEPLOYMENT_BLOCK not set, %s starts indexing at head block %d", name, head)
+	return head + 1, nil
+}
+
+// replayRegistrations rewinds a cursor stored before RecordRegistered was
+// indexed to the deployment block, once, so earlier registrations are picked
+// up. Replaying the consent logs on the way is a no-op.
+func replayRegistrations(name string, fromBlock uint64, deploymentBlock uint64, configured bool) (uint64, error) {
+	_, upgraded, err := repositories.GetIndexerCursor(name + registrationMarker)
+	if err != nil {
+		return 0, err
+	}
+	if upgraded {
+		return fromBlock, nil
+	}
+
+	if !configured {
+		log.Printf("CONTRACT_DEPLOYMENT_BLOCK not set, %s logs before block %d are not replayed", recordRegistered, fromBlock)
+	} else if deploymentBlock < fromBlock {
+		log.Printf("Replaying %s from block %d to index %s logs", name, deploymentBlock, recordRegistered)
+		if err := repositories.RewindIndexerCursors(name, max(deploymentBlock, 1)-1); err != nil {
+			return 0, err
+		}
+		fromBlock = deploymentBlock
+	}
+
+	if err := repositories.SaveIndexerCursor(name+registrationMarker, 0); err != nil {
+		return 0, err
+	}
+	return fromBlock, nil
+}
+
+// backfillEvents replays logs in [fromBlock, toBlock] in chunks, in (block
+// number, log index) order, saving the cursor after each chunk so an
+// interrupted backfill resumes where it stopped.
+func backfillEvents(
+	ctx context.Context,
+	wsClient *ethclient.Client,
+	contractAddr common.Address,
+	parsedABI abi.ABI,
+	topics []common.Hash,
+	fromBlock uint64,
+	toBlock uint64,
+) error {
+	if fromBlock > toBlock {
+		return nil
+	}
+
+	name := cursorName(contractAddr)
+	chunkSize := getBackfillChunkSize()
+	confirmations := getConfirmations()
+
+	log.Printf("Backfilling %s from block %d to %d", name, fromBlock, toBlock)
+	for start := fromBlock; start <= toBlock; start += chunkSize {
+		end := min(start+chunkSize-1, toBlock)
+
+		logs, err := wsClient.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{contractAddr},
+			Topics:    [][]common.Hash{topics},
+		})
+		if err != nil {
+			return fmt.Errorf("filter logs %d-%d: %w", start, end, err)
+		}
+
+		sortLogs(logs)
+		for _, lg := range logs {
+			recordBlock(lg.BlockNumber)
+			if err := handleLog(ctx, wsClient, parsedABI, lg, isConfirmed(lg.BlockNumber, toBlock, confirmations)); err != nil {
+				return err
+			}
+		}
+
+		if err := repositories.SaveIndexerCursor(name, end); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Backfill of %s complete at block %d", name, toBlock)
+	return nil
+}
+
+// sortLogs orders logs by (block number, log index). Nodes already return them
+// in that order, but state transitions depend on it so it is not assumed.
+func sortLogs(logs []types.Log) {
+	slices.SortStableFunc(logs, func(a, b types.Log) int {
+		if c := cmp.Compare(a.BlockNumber, b.BlockNumber); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Index, b.Index)
+	})
+}
+
+func getDeploymentBlock() (uint64, bool, error) {
+	value := os.Getenv("CONTRACT_DEPLOYMENT_BLOCK")
+	if value == "" {
+		return 0, false, nil
+	}
+
+	block, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid CONTRACT_DEPLOYMENT_BLOCK: %w", err)
+	}
+	return block, true, nil
+}
+
+func getBackfillChunkSize() uint64 {
+	value := os.Getenv("INDEXER_BACKFILL_CHUNK_SIZE")
+	if value == "" {
+		return defaultBackfillChunkSize
+	}
+
+	size, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || size == 0 {
+		log.Printf("Invalid INDEXER_BACKFILL_CHUNK_SIZE %q, using %d", value, defaultBackfillChunkSize)
+		return defaultBackfillChunkSize
+	}
+	return size
+}
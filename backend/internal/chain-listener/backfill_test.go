@@ -0,0 +1,85 @@
+package chainlistener
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestCursorName(t *testing.T) {
+	addr := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2")
+
+	got := cursorName(addr)
+	want := "0x742d35cc6634c0532925a3b844bc9e7595f0beb2"
+	if got != want {
+		t.Errorf("cursorName() = %v, want %v", got, want)
+	}
+}
+
+func TestGetDeploymentBlock(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		wantBlock      uint64
+		wantConfigured bool
+		wantErr        bool
+	}{
+		{"Not set", "", 0, false, false},
+		{"Valid block", "7234567", 7234567, true, false},
+		{"Invalid block", "latest", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONTRACT_DEPLOYMENT_BLOCK", tt.value)
+
+			block, configured, err := getDeploymentBlock()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getDeploymentBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if block != tt.wantBlock || configured != tt.wantConfigured {
+				t.Errorf("getDeploymentBlock() = (%d, %v), want (%d, %v)", block, configured, tt.wantBlock, tt.wantConfigured)
+			}
+		})
+	}
+}
+
+func TestGetBackfillChunkSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint64
+	}{
+		{"", defaultBackfillChunkSize},
+		{"500", 500},
+		{"0", defaultBackfillChunkSize},
+		{"abc", defaultBackfillChunkSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("INDEXER_BACKFILL_CHUNK_SIZE", tt.value)
+			if got := getBackfillChunkSize(); got != tt.want {
+				t.Errorf("getBackfillChunkSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortLogs(t *testing.T) {
+	logs := []types.Log{
+		{BlockNumber: 12, Index: 0},
+		{BlockNumber: 10, Index: 3},
+		{BlockNumber: 10, Index: 1},
+		{BlockNumber: 11, Index: 7},
+	}
+
+	sortLogs(logs)
+
+	want := [][2]uint64{{10, 1}, {10, 3}, {11, 7}, {12, 0}}
+	for i, lg := range logs {
+		if lg.BlockNumber != want[i][0] || uint64(lg.Index) != want[i][1] {
+			t.Errorf("position %d: got (%d, %d), want (%d, %d)", i, lg.BlockNumber, lg.Index, want[i][0], want[i][1])
+		}
+	}
+}
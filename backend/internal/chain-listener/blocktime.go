@@ -0,0 +1,54 @@
+package chainlistener
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const blockTimeCacheSize = 1024
+
+type headerReader interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// blockTimes caches block timestamps by hash, so a block holding several
+// consent events is only fetched once.
+var blockTimes = struct {
+	mu    sync.Mutex
+	times map[common.Hash]time.Time
+}{times: make(map[common.Hash]time.Time)}
+
+// blockTimestamp returns the time of the block a log was emitted in. Nodes
+// that fill in the log's block timestamp save the header lookup.
+func blockTimestamp(ctx context.Context, client headerReader, lg types.Log) (time.Time, error) {
+	if lg.BlockTimestamp != 0 {
+		return time.Unix(int64(lg.BlockTimestamp), 0).UTC(), nil
+	}
+
+	blockTimes.mu.Lock()
+	cached, ok := blockTimes.times[lg.BlockHash]
+	blockTimes.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	header, err := client.HeaderByHash(ctx, lg.BlockHash)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetch header %s: %w", lg.BlockHash.Hex(), err)
+	}
+	timestamp := time.Unix(int64(header.Time), 0).UTC()
+
+	blockTimes.mu.Lock()
+	if len(blockTimes.times) >= blockTimeCacheSize {
+		clear(blockTimes.times)
+	}
+	blockTimes.times[lg.BlockHash] = timestamp
+	blockTimes.mu.Unlock()
+
+	return timestamp, nil
+}
@@ -0,0 +1,55 @@
+package chainlistener
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeHeaderReader struct {
+	calls int
+	time  uint64
+}
+
+func (f *fakeHeaderReader) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	f.calls++
+	return &types.Header{Number: big.NewInt(1), Time: f.time}, nil
+}
+
+func TestBlockTimestamp(t *testing.T) {
+	reader := &fakeHeaderReader{time: 1700000000}
+
+	t.Run("uses log timestamp when set", func(t *testing.T) {
+		lg := types.Log{BlockHash: common.HexToHash("0x01"), BlockTimestamp: 1600000000}
+		got, err := blockTimestamp(context.Background(), reader, lg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.Equal(time.Unix(1600000000, 0)) {
+			t.Errorf("expected log timestamp, got %v", got)
+		}
+		if reader.calls != 0 {
+			t.Errorf("expected no header lookup, got %d", reader.calls)
+		}
+	})
+
+	t.Run("fetches header once per block", func(t *testing.T) {
+		lg := types.Log{BlockHash: common.HexToHash("0x02")}
+		for range 3 {
+			got, err := blockTimestamp(context.Background(), reader, lg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("expected header timestamp, got %v", got)
+			}
+		}
+		if reader.calls != 1 {
+			t.Errorf("expected 1 header lookup, got %d", reader.calls)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package chainlistener
+
+import (
+	"consentis-api/internal/access"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	defaultConfirmations = 6
+	finalityPollInterval = 12 * time.Second
+)
+
+var errReorgDetected = errors.New("chain reorganisation detected")
+
+// isConfirmed reports whether a log in blockNumber has reached the configured
+// depth. The head block itself counts as one confirmation.
+func isConfirmed(blockNumber uint64, head uint64, confirmations uint64) bool {
+	if confirmations == 0 {
+		return true
+	}
+	return head >= blockNumber && head-blockNumber+1 >= confirmations
+}
+
+// trackFinality periodically promotes pending consent logs that reached the
+// confirmation depth and checks the block hash each one was seen in. When a
+// stored hash no longer matches the canonical chain the affected logs are
+// rolled back, the cursors rewound, and errReorgDetected returned so the
+// session reconnects and backfills the canonical logs. Once the contract
+// stream is live it also flags records that missed the registration window.
+func trackFinality(ctx context.Context, wsClient *ethclient.Client, contractAddr common.Address, registrationWindow time.Duration) error {
+	confirmations := getConfirmations()
+	ticker := time.NewTicker(finalityPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := reconcileUnconfirmed(ctx, wsClient, contractAddr, confirmations); err != nil {
+				return err
+			}
+			if GetStatus().Subscriptions[contractStream] {
+				flagUnregisteredRecords(registrationWindow)
+			}
+		}
+	}
+}
+
+func reconcileUnconfirmed(ctx context.Context, wsClient *ethclient.Client, contractAddr common.Address, confirmations uint64) error {
+	entries, err := repositories.GetUnconfirmedConsentLogs()
+	if err != nil {
+		return fmt.Errorf("load unconfirmed logs: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	head, err := wsClient.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch head block: %w", err)
+	}
+
+	canonical := make(map[uint64]string)
+	for i, entry := range entries {
+		hash, ok := canonical[entry.BlockNumber]
+		if !ok {
+			header, err := wsClient.HeaderByNumber(ctx, new(big.Int).SetUint64(entry.BlockNumber))
+			if err != nil {
+				return fmt.Errorf("fetch header %d: %w", entry.BlockNumber, err)
+			}
+			hash = header.Hash().Hex()
+			canonical[entry.BlockNumber] = hash
+		}
+
+		if !strings.EqualFold(hash, entry.BlockHash) {
+			log.Printf("Block %d hash changed from %s to %s", entry.BlockNumber, entry.BlockHash, hash)
+			if err := rollbackFrom(contractAddr, entries[i:]); err != nil {
+				return err
+			}
+			return errReorgDetected
+		}
+
+		if !isConfirmed(entry.BlockNumber, head, confirmations) {
+			continue
+		}
+		if err := repositories.ConfirmConsentLog(entry.TxHash, entry.LogIndex); err != nil {
+			return fmt.Errorf("confirm log: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rollbackFrom undoes entries newest first, then rewinds the cursors to the
+// block before the first reorged one so those blocks are indexed again.
+func rollbackFrom(contractAddr common.Address, entries []models.ConsentLog) error {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := repositories.RollbackConsentLog(entries[i].TxHash, entries[i].LogIndex); err != nil {
+			return fmt.Errorf("rollback log: %w", err)
+		}
+		access.GetVerifier().Invalidate(entries[i].RecordID, entries[i].ResearcherAddress)
+		reopenAccessRequests(entries[i].TxHash)
+	}
+
+	return repositories.RewindIndexerCursors(cursorName(contractAddr), entries[0].BlockNumber-1)
+}
+
+func getConfirmations() uint64 {
+	value := os.Getenv("INDEXER_CONFIRMATIONS")
+	if value == "" {
+		return defaultConfirmations
+	}
+
+	confirmations, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid INDEXER_CONFIRMATIONS %q, using %d", value, defaultConfirmations)
+		return defaultConfirmations
+	}
+	return confirmations
+}
@@ -0,0 +1,49 @@
+package chainlistener
+
+import "testing"
+
+func TestIsConfirmed(t *testing.T) {
+	tests := []struct {
+		name          string
+		block         uint64
+		head          uint64
+		confirmations uint64
+		want          bool
+	}{
+		{"No confirmations required", 100, 100, 0, true},
+		{"Head block with depth 1", 100, 100, 1, true},
+		{"Head block with depth 6", 100, 100, 6, false},
+		{"Exactly deep enough", 100, 105, 6, true},
+		{"One block short", 100, 104, 6, false},
+		{"Head behind the log", 100, 99, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConfirmed(tt.block, tt.head, tt.confirmations); got != tt.want {
+				t.Errorf("isConfirmed(%d, %d, %d) = %v, want %v", tt.block, tt.head, tt.confirmations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConfirmations(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint64
+	}{
+		{"", defaultConfirmations},
+		{"0", 0},
+		{"12", 12},
+		{"-1", defaultConfirmations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("INDEXER_CONFIRMATIONS", tt.value)
+			if got := getConfirmations(); got != tt.want {
+				t.Errorf("getConfirmations() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,16 @@ package chainlistener
 
 import (
 	"bytes"
+	"consentis-api/internal/access"
 	"consentis-api/internal/models"
 	"consentis-api/internal/repositories"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -20,14 +23,22 @@ import (
 const defaultABIPath = "contracts/ConsentRegistry.abi"
 const consentGranted = "ConsentGranted"
 const consentRevoked = "ConsentRevoked"
+const recordRegistered = "RecordRegistered"
+const contractStream = "ConsentRegistry"
+const dialTimeout = 15 * time.Second
 
-func startWebSocketConnection(ctx context.Context) *ethclient.Client {
-	ethClientAddress, err := getEthClientAddress()
-	wsClient, err := ethclient.DialContext(ctx, ethClientAddress)
+// indexedEvents are the contract events consumed by the listener
+var indexedEvents = []string{recordRegistered, consentGranted, consentRevoked}
+
+func startWebSocketConnection(ctx context.Context, ethClientAddress string) (*ethclient.Client, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	wsClient, err := ethclient.DialContext(dialCtx, ethClientAddress)
 	if err != nil {
-		log.Fatal("websocket dial:", err)
+		return nil, fmt.Errorf("websocket dial: %w", err)
 	}
-	return wsClient
+	return wsClient, nil
 }
 
 func StartEventListener(ctx context.Context) {
@@ -38,9 +49,12 @@ func StartEventListener(ctx context.Context) {
 		log.Fatal(err)
 	}
 
+	ethClientAddress, err := getEthClientAddress()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	contractAddr := common.HexToAddress(contractAddress)
-	wsClient := startWebSocketConnection(ctx)
-	defer wsClient.Close()
 
 	abiBytes, err := os.ReadFile(defaultABIPath)
 	if err != nil {
@@ -52,58 +66,254 @@ func StartEventListener(ctx context.Context) {
 		log.Fatal("parse abi:", err)
 	}
 
+	registrationWindow, err := getRegistrationWindow()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	superviseListener(ctx, func(ctx context.Context) error {
+		return runListenerSession(ctx, ethClientAddress, contractAddr, parsedABI, registrationWindow)
+	})
+
+	log.Println("Chain event listener stopped")
+}
+
+// runListenerSession dials the node and runs the contract log stream and the
+// finality tracker until either fails or ctx is cancelled. A failure cancels
+// the other so the supervisor can reconnect from the stored cursor.
+func runListenerSession(ctx context.Context, ethClientAddress string, contractAddr common.Address, parsedABI abi.ABI, registrationWindow time.Duration) error {
+	if err := fillRecordIDHashes(); err != nil {
+		return fmt.Errorf("fill record id hashes: %w", err)
+	}
+
+	wsClient, err := startWebSocketConnection(ctx, ethClientAddress)
+	if err != nil {
+		return err
+	}
+	defer wsClient.Close()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	registerSubscriptions([]string{contractStream})
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		listenToEventCreation(contractAddr, parsedABI, ctx, wsClient, consentGranted)
+		if err := listenToContractEvents(sessionCtx, wsClient, contractAddr, parsedABI); err != nil {
+			errs <- fmt.Errorf("%s: %w", contractStream, err)
+		}
+		cancel()
 	}()
 
 	go func() {
 		defer wg.Done()
-		listenToEventCreation(contractAddr, parsedABI, ctx, wsClient, consentRevoked)
+		if err := trackFinality(sessionCtx, wsClient, contractAddr, registrationWindow); err != nil {
+			errs <- fmt.Errorf("finality: %w", err)
+		}
+		cancel()
 	}()
 
-	<-ctx.Done()
-	log.Println("Context cancelled, waiting for listeners to finish...")
-
 	wg.Wait()
+	close(errs)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	for err := range errs {
+		return err
+	}
+	return errors.New("listener session ended unexpectedly")
 }
 
-func listenToEventCreation(contractAddr common.Address, parsedABI abi.ABI, ctx context.Context, wsClient *ethclient.Client, eventName string) {
-	events, ok := parsedABI.Events[eventName]
-	if !ok {
-		log.Fatalf("event %v not found in ABI", eventName)
+// eventTopics returns the topic0 of every indexed event, so a single
+// subscription delivers them in chain order.
+func eventTopics(parsedABI abi.ABI) ([]common.Hash, error) {
+	topics := make([]common.Hash, 0, len(indexedEvents))
+	for _, eventName := range indexedEvents {
+		event, ok := parsedABI.Events[eventName]
+		if !ok {
+			return nil, fmt.Errorf("event %v not found in ABI", eventName)
+		}
+		topics = append(topics, event.ID)
+	}
+	return topics, nil
+}
+
+// listenToContractEvents processes every indexed event of the contract as one
+// stream ordered by (block number, log index), backfilling from the cursor
+// before switching to the live subscription.
+func listenToContractEvents(ctx context.Context, wsClient *ethclient.Client, contractAddr common.Address, parsedABI abi.ABI) error {
+	topics, err := eventTopics(parsedABI)
+	if err != nil {
+		return err
 	}
-	topic0 := events.ID
 
+	// Subscribe before reading the head so nothing emitted during the backfill
+	// is missed, the node buffers live logs until we start reading them.
 	ch := make(chan types.Log)
 	sub, err := wsClient.SubscribeFilterLogs(ctx, ethereum.FilterQuery{
 		Addresses: []common.Address{contractAddr},
-		Topics:    [][]common.Hash{{topic0}},
+		Topics:    [][]common.Hash{topics},
 	}, ch)
 	if err != nil {
-		log.Fatal("subscribe:", err)
+		setSubscribed(contractStream, false, err)
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	head, err := wsClient.BlockNumber(ctx)
+	if err != nil {
+		setSubscribed(contractStream, false, err)
+		return fmt.Errorf("fetch head block: %w", err)
+	}
+
+	name := cursorName(contractAddr)
+	fromBlock, err := resolveStartBlock(name, head)
+	if err != nil {
+		setSubscribed(contractStream, false, err)
+		return fmt.Errorf("resolve start block: %w", err)
+	}
+
+	if err := backfillEvents(ctx, wsClient, contractAddr, parsedABI, topics, fromBlock, head); err != nil {
+		setSubscribed(contractStream, false, err)
+		return fmt.Errorf("backfill: %w", err)
 	}
+	setSubscribed(contractStream, true, nil)
 
+	confirmations := getConfirmations()
+	currentBlock := head
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("shutting down listener")
-			return
+			setSubscribed(contractStream, false, nil)
+			return nil
 
 		case err := <-sub.Err():
-			log.Println("subscription error:", err)
-			return
+			setSubscribed(contractStream, false, err)
+			return fmt.Errorf("subscription: %w", err)
 
 		case lg := <-ch:
-			SaveConsent(parsedABI, lg, eventName)
+			if lg.Removed {
+				// The block was reorged out, undo the log and index that
+				// height again when the replacement logs arrive.
+				if err := rollbackLog(parsedABI, lg); err != nil {
+					setSubscribed(contractStream, false, err)
+					return err
+				}
+				if err := repositories.RewindIndexerCursors(name, lg.BlockNumber-1); err != nil {
+					log.Println("rewind cursor:", err)
+				}
+				head = min(head, lg.BlockNumber-1)
+				currentBlock = min(currentBlock, lg.BlockNumber-1)
+				continue
+			}
+
+			// Already covered by the backfill
+			if lg.BlockNumber <= head {
+				continue
+			}
+
+			// Logs arrive in block order, so a log from a newer block means
+			// every log of the previous one has been handled.
+			if lg.BlockNumber > currentBlock {
+				if err := repositories.SaveIndexerCursor(name, lg.BlockNumber-1); err != nil {
+					log.Println("save cursor:", err)
+				}
+				currentBlock = lg.BlockNumber
+			}
+
+			// A live log sits in the newest block, so it only counts as final
+			// when no extra confirmations are required.
+			recordBlock(lg.BlockNumber)
+			if err := handleLog(ctx, wsClient, parsedABI, lg, isConfirmed(lg.BlockNumber, lg.BlockNumber, confirmations)); err != nil {
+				// Leave the cursor behind this block so the reconnect replays it
+				setSubscribed(contractStream, false, err)
+				return err
+			}
 		}
 	}
 }
 
-func SaveConsent(parsedABI abi.ABI, lg types.Log, eventName string) {
+// handleLog dispatches a log to the handler of its event.
+func handleLog(ctx context.Context, client headerReader, parsedABI abi.ABI, lg types.Log, confirmed bool) error {
+	if len(lg.Topics) == 0 {
+		return nil
+	}
+
+	event, err := parsedABI.EventByID(lg.Topics[0])
+	if err != nil {
+		log.Printf("Skipping log with unknown topic %s tx=%s", lg.Topics[0].Hex(), lg.TxHash.Hex())
+		return nil
+	}
+
+	switch event.Name {
+	case recordRegistered:
+		if err := SaveRecordRegistration(lg); err != nil {
+			return fmt.Errorf("save record registration: %w", err)
+		}
+	case consentGranted, consentRevoked:
+		blockTime, err := blockTimestamp(ctx, client, lg)
+		if err != nil {
+			return err
+		}
+		if err := SaveConsent(parsedABI, lg, event.Name, blockTime, confirmed); err != nil {
+			return fmt.Errorf("save consent: %w", err)
+		}
+	}
+	return nil
+}
+
+// rollbackLog undoes a log removed from the canonical chain.
+func rollbackLog(parsedABI abi.ABI, lg types.Log) error {
+	if len(lg.Topics) == 0 {
+		return nil
+	}
+
+	event, err := parsedABI.EventByID(lg.Topics[0])
+	if err != nil {
+		return nil
+	}
+
+	switch event.Name {
+	case recordRegistered:
+		if err := repositories.DiscardDeferredLog(lg.TxHash.Hex(), lg.Index); err != nil {
+			return fmt.Errorf("discard deferred registration: %w", err)
+		}
+		recordID, err := repositories.RollbackRecordRegistration(lg.TxHash.Hex())
+		if err != nil {
+			return fmt.Errorf("rollback record registration: %w", err)
+		}
+		if recordID != "" {
+			access.GetVerifier().Invalidate(recordID, "")
+		}
+	case consentGranted, consentRevoked:
+		if err := repositories.DiscardDeferredLog(lg.TxHash.Hex(), lg.Index); err != nil {
+			return fmt.Errorf("discard deferred consent: %w", err)
+		}
+		if err := repositories.RollbackConsentLog(lg.TxHash.Hex(), lg.Index); err != nil {
+			return fmt.Errorf("rollback consent: %w", err)
+		}
+		reopenAccessRequests(lg.TxHash.Hex())
+		var out struct {
+			RecordId string
+		}
+		if err := parsedABI.UnpackIntoInterface(&out, event.Name, lg.Data); err == nil && len(lg.Topics) > 2 {
+			access.GetVerifier().Invalidate(out.RecordId, common.BytesToAddress(lg.Topics[2].Bytes()).Hex())
+		}
+	}
+	return nil
+}
+
+// SaveConsent appends a consent event to the history and applies it to the
+// consents table, as "pending" until confirmed. Logs that cannot be decoded or
+// that the database permanently rejects are skipped, other database errors are
+// returned to the caller.
+func SaveConsent(parsedABI abi.ABI, lg types.Log, eventName string, blockTime time.Time, confirmed bool) error {
 	patient := common.BytesToAddress(lg.Topics[1].Bytes())
 	researcher := common.BytesToAddress(lg.Topics[2].Bytes())
 
@@ -114,27 +324,67 @@ func SaveConsent(parsedABI abi.ABI, lg types.Log, eventName string) {
 	if err := parsedABI.UnpackIntoInterface(&out, eventName, lg.Data); err != nil {
 		log.Printf("unpack error: %v", err)
 		log.Printf("Event data (hex): %x", lg.Data)
-		return
+		return nil
 	}
 
-	status := "pending"
+	var status string
 	switch eventName {
 	case consentGranted:
 		status = "granted"
 	case consentRevoked:
 		status = "revoked"
+	default:
+		return fmt.Errorf("unexpected consent event %s", eventName)
 	}
-	consent := models.Consent{
+	entry := models.ConsentLog{
+		TxHash:            lg.TxHash.Hex(),
+		LogIndex:          lg.Index,
+		BlockNumber:       lg.BlockNumber,
+		BlockHash:         lg.BlockHash.Hex(),
+		BlockTimestamp:    blockTime,
+		EventType:         eventName,
+		RecordID:          out.RecordId,
 		PatientAddress:    patient.String(),
 		ResearcherAddress: researcher.String(),
-		RecordID:          out.RecordId,
 		Status:            status,
+		Confirmed:         confirmed,
 	}
 
-	err := repositories.SaveConsent(consent, lg.TxHash.Hex())
+	// The contract state changed whether or not the log is new to us
+	access.GetVerifier().Invalidate(out.RecordId, researcher.Hex())
+
+	applied, err := repositories.ApplyConsentLog(entry)
+	if repositories.IsMissingRecordError(err) {
+		// A consent granted while the record's upload is still open.
+		var deferred bool
+		deferred, err = repositories.DeferConsentLog(entry)
+		if err == nil && deferred {
+			log.Printf("Consent log tx=%s index=%d waits for the upload of record %s", entry.TxHash, entry.LogIndex, entry.RecordID)
+			return nil
+		}
+		if err == nil {
+			// The upload may have completed in the meantime.
+			applied, err = repositories.ApplyConsentLog(entry)
+		}
+	}
 	if err != nil {
-		log.Println(err)
-		return
+		if repositories.IsPermanentError(err) {
+			log.Printf("Skipping consent log tx=%s index=%d: %v", lg.TxHash.Hex(), lg.Index, err)
+			return nil
+		}
+		return err
+	}
+
+	if eventName == consentGranted {
+		fulfillAccessRequests(entry)
+	}
+
+	if !applied {
+		log.Printf("Consent log tx=%s index=%d already applied, skipping", lg.TxHash.Hex(), lg.Index)
+		return nil
+	}
+	if !confirmed {
+		status = "pending"
 	}
 
 	log.Printf("Consent %s patient=%s researcher=%s recordId=%s txHash=%s block=%d\n",
@@ -145,6 +395,26 @@ func SaveConsent(parsedABI abi.ABI, lg types.Log, eventName string) {
 		lg.TxHash.Hex(),
 		lg.BlockNumber,
 	)
+	return nil
+}
+
+// fulfillAccessRequests closes the access requests answered by a grant. A
+// failure only leaves the requests open, so it does not stop the indexer.
+func fulfillAccessRequests(entry models.ConsentLog) {
+	fulfilled, err := repositories.FulfillAccessRequests(entry.RecordID, entry.ResearcherAddress, entry.TxHash, entry.BlockTimestamp)
+	if err != nil {
+		log.Println("fulfill access requests:", err)
+		return
+	}
+	if fulfilled > 0 {
+		log.Printf("Fulfilled %d access requests for record %s researcher=%s", fulfilled, entry.RecordID, entry.ResearcherAddress)
+	}
+}
+
+func reopenAccessRequests(txHash string) {
+	if err := repositories.ReopenAccessRequests(txHash); err != nil {
+		log.Println("reopen access requests:", err)
+	}
 }
 
 func getContractAddress() (string, error) {
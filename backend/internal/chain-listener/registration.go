@@ -0,0 +1,122 @@
+package chainlistener
+
+import (
+	"consentis-api/internal/access"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const defaultRegistrationWindow = time.Hour
+
+// registrationMarker is appended to the contract cursor name to record that
+// the cursor covers RecordRegistered logs, which older cursors skipped. It is
+// not ':' separated like the former per-event cursors, which migration 0005
+// folds into the contract cursor and deletes.
+const registrationMarker = "#" + recordRegistered
+
+// SaveRecordRegistration reconciles a RecordRegistered log with the records
+// table. The record id is an indexed string, so the log only carries its hash.
+func SaveRecordRegistration(lg types.Log) error {
+	if len(lg.Topics) < 3 {
+		log.Printf("Skipping malformed %s log tx=%s", recordRegistered, lg.TxHash.Hex())
+		return nil
+	}
+
+	reg := models.RecordRegistration{
+		RecordIDHash: lg.Topics[1].Hex(),
+		Owner:        common.BytesToAddress(lg.Topics[2].Bytes()).Hex(),
+		TxHash:       lg.TxHash.Hex(),
+		LogIndex:     lg.Index,
+		BlockNumber:  lg.BlockNumber,
+		BlockHash:    lg.BlockHash.Hex(),
+	}
+
+	recordID, status, found, err := repositories.ApplyRecordRegistration(reg)
+	if err == nil && !found {
+		// The patient registers the record as soon as the upload is
+		// accepted, usually before the upload worker inserts it.
+		var deferred bool
+		deferred, err = repositories.DeferRecordRegistration(reg)
+		if err == nil && deferred {
+			log.Printf("Record registration idHash=%s tx=%s waits for its upload", reg.RecordIDHash, reg.TxHash)
+			return nil
+		}
+		if err == nil {
+			// The upload may have completed in the meantime.
+			recordID, status, found, err = repositories.ApplyRecordRegistration(reg)
+		}
+	}
+	if err != nil {
+		if repositories.IsPermanentError(err) {
+			log.Printf("Skipping record registration tx=%s: %v", reg.TxHash, err)
+			return nil
+		}
+		return err
+	}
+	if !found {
+		log.Printf("Record registered on-chain with no matching record idHash=%s owner=%s tx=%s", reg.RecordIDHash, reg.Owner, reg.TxHash)
+		return nil
+	}
+
+	access.GetVerifier().Invalidate(recordID, "")
+
+	if status == "owner_mismatch" {
+		log.Printf("Record %s registered on-chain by %s, which is not its patient tx=%s", recordID, reg.Owner, reg.TxHash)
+		return nil
+	}
+	log.Printf("Record %s registered owner=%s txHash=%s block=%d", recordID, reg.Owner, reg.TxHash, reg.BlockNumber)
+	return nil
+}
+
+// fillRecordIDHashes stores the id hash of records created before it was
+// saved on insert, so their registrations can be matched.
+func fillRecordIDHashes() error {
+	ids, err := repositories.GetRecordIDsWithoutHash()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := repositories.SetRecordIDHash(id, helpers.RecordIDHash(id)); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Stored on-chain id hash of %d records", len(ids))
+	}
+	return nil
+}
+
+// flagUnregisteredRecords marks records not registered on-chain within the
+// registration window. It is only meaningful once the listener is caught up.
+func flagUnregisteredRecords(window time.Duration) {
+	ids, err := repositories.MarkUnregisteredRecords(window)
+	if err != nil {
+		log.Println("flag unregistered records:", err)
+		return
+	}
+	for _, id := range ids {
+		log.Printf("Record %s was not registered on-chain within %s", id, window)
+	}
+}
+
+func getRegistrationWindow() (time.Duration, error) {
+	value := os.Getenv("RECORD_REGISTRATION_WINDOW")
+	if value == "" {
+		return defaultRegistrationWindow, nil
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid RECORD_REGISTRATION_WINDOW %q", value)
+	}
+	return window, nil
+}
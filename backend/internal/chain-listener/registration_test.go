@@ -0,0 +1,34 @@
+package chainlistener
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetRegistrationWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"Not set", "", defaultRegistrationWindow, false},
+		{"Valid duration", "30m", 30 * time.Minute, false},
+		{"Not a duration", "soon", 0, true},
+		{"Negative", "-1h", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RECORD_REGISTRATION_WINDOW", tt.value)
+
+			got, err := getRegistrationWindow()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRegistrationWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getRegistrationWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
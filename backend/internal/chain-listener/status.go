@@ -0,0 +1,112 @@
+package chainlistener
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateStopped      = "stopped"
+)
+
+// ListenerStatus is a snapshot of the indexer state for health reporting.
+type ListenerStatus struct {
+	State         string
+	Reconnects    int
+	ConnectedAt   time.Time
+	Subscribed    bool
+	Subscriptions map[string]bool
+	LastBlock     uint64
+	LastEventAt   time.Time
+	LastError     string
+}
+
+var (
+	statusMu sync.RWMutex
+	status   = ListenerStatus{State: StateConnecting, Subscriptions: map[string]bool{}}
+)
+
+func GetStatus() ListenerStatus {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+
+	subscriptions := make(map[string]bool, len(status.Subscriptions))
+	subscribed := status.State == StateConnected && len(status.Subscriptions) > 0
+	for name, active := range status.Subscriptions {
+		subscriptions[name] = active
+		subscribed = subscribed && active
+	}
+
+	snapshot := status
+	snapshot.Subscriptions = subscriptions
+	snapshot.Subscribed = subscribed
+	return snapshot
+}
+
+func setSubscribed(stream string, active bool, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	status.Subscriptions[stream] = active
+	if err != nil {
+		status.LastError = err.Error()
+	}
+
+	if !active {
+		return
+	}
+	for _, subscribed := range status.Subscriptions {
+		if !subscribed {
+			return
+		}
+	}
+	if status.State != StateConnected {
+		status.State = StateConnected
+		status.ConnectedAt = time.Now()
+		log.Println("Chain listener connected, all event subscriptions active")
+	}
+}
+
+// registerSubscriptions marks the streams of a new session as inactive so the
+// listener only reports connected once every one of them is subscribed.
+func registerSubscriptions(streams []string) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	status.Subscriptions = make(map[string]bool, len(streams))
+	for _, name := range streams {
+		status.Subscriptions[name] = false
+	}
+}
+
+func setConnectionState(state string, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	if state == StateReconnecting {
+		status.Reconnects++
+	}
+	if state != StateConnected {
+		for name := range status.Subscriptions {
+			status.Subscriptions[name] = false
+		}
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	status.State = state
+}
+
+func recordBlock(blockNumber uint64) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	if blockNumber > status.LastBlock {
+		status.LastBlock = blockNumber
+	}
+	status.LastEventAt = time.Now()
+}
@@ -0,0 +1,63 @@
+package chainlistener
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 1 * time.Minute
+	// A session that stayed up this long resets the backoff
+	stableSession = 2 * time.Minute
+)
+
+// superviseListener runs session until ctx is cancelled, reconnecting with
+// exponential backoff and jitter whenever it fails. Each new session resumes
+// from the stored block cursors, so nothing emitted while disconnected is lost.
+func superviseListener(ctx context.Context, session func(ctx context.Context) error) {
+	attempt := 0
+	for {
+		setConnectionState(StateConnecting, nil)
+		startedAt := time.Now()
+
+		err := session(ctx)
+		if ctx.Err() != nil {
+			setConnectionState(StateStopped, nil)
+			return
+		}
+
+		if time.Since(startedAt) >= stableSession {
+			attempt = 0
+		}
+
+		delay := backoffDelay(attempt)
+		attempt++
+
+		setConnectionState(StateReconnecting, err)
+		log.Printf("Chain listener degraded: %v. Reconnecting in %s (attempt %d)", err, delay.Round(time.Millisecond), attempt)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			setConnectionState(StateStopped, nil)
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// backoffDelay doubles from initialBackoff up to maxBackoff and picks a random
+// point in the upper half so restarted replicas do not reconnect in lockstep.
+func backoffDelay(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 16 {
+		delay = min(initialBackoff<<attempt, maxBackoff)
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,57 @@
+package chainlistener
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, initialBackoff},
+		{1, 2 * initialBackoff},
+		{3, 8 * initialBackoff},
+		{10, maxBackoff},
+		{100, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := backoffDelay(tt.attempt)
+			if delay < tt.ceiling/2 || delay > tt.ceiling {
+				t.Errorf("backoffDelay(%d) = %s, want within [%s, %s]", tt.attempt, delay, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+func TestListenerStatusTransitions(t *testing.T) {
+	registerSubscriptions([]string{contractStream, "other"})
+	setConnectionState(StateConnecting, nil)
+
+	setSubscribed(contractStream, true, nil)
+	if got := GetStatus(); got.Subscribed || got.State != StateConnecting {
+		t.Errorf("Expected connecting while one stream is pending, got state=%s subscribed=%v", got.State, got.Subscribed)
+	}
+
+	setSubscribed("other", true, nil)
+	if got := GetStatus(); !got.Subscribed || got.State != StateConnected {
+		t.Errorf("Expected connected, got state=%s subscribed=%v", got.State, got.Subscribed)
+	}
+
+	reconnects := GetStatus().Reconnects
+	setConnectionState(StateReconnecting, errors.New("websocket closed"))
+	got := GetStatus()
+	if got.Subscribed || got.State != StateReconnecting {
+		t.Errorf("Expected reconnecting, got state=%s subscribed=%v", got.State, got.Subscribed)
+	}
+	if got.Reconnects != reconnects+1 {
+		t.Errorf("Expected reconnects to increase to %d, got %d", reconnects+1, got.Reconnects)
+	}
+	if got.LastError != "websocket closed" {
+		t.Errorf("Expected last error to be recorded, got %q", got.LastError)
+	}
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMigrations(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name: "Sorted by version",
+			files: fstest.MapFS{
+				"migrations/0010_later.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+				"migrations/0010_later.down.sql": {Data: []byte("DROP TABLE b;")},
+				"migrations/0002_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"migrations/0002_first.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			wantVersions: []int64{2, 10},
+		},
+		{
+			name:         "Empty directory",
+			files:        fstest.MapFS{"migrations": {Mode: fs.ModeDir}},
+			wantVersions: []int64{},
+		},
+		{
+			name: "Missing down file",
+			files: fstest.MapFS{
+				"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Conflicting names",
+			files: fstest.MapFS{
+				"migrations/0001_init.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+				"migrations/0001_other.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid file name",
+			files: fstest.MapFS{
+				"migrations/init.sql": {Data: []byte("CREATE TABLE a ();")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Version zero",
+			files: fstest.MapFS{
+				"migrations/0000_init.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"migrations/0000_init.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := parseMigrations(tt.files, "migrations")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(migrations) != len(tt.wantVersions) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.wantVersions))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.wantVersions[i] {
+					t.Errorf("migration %d version = %d, want %d", i, m.Version, tt.wantVersions[i])
+				}
+				if m.Up == "" || m.Down == "" {
+					t.Errorf("migration %d is missing its up or down script", m.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	// Versions are numbered without gaps so a missing file shows up here.
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s, want version %d", m.Version, m.Name, i+1)
+		}
+	}
+
+	if migrations[0].Name != "init" {
+		t.Errorf("first migration = %s, want init", migrations[0].Name)
+	}
+}
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS update_consents_updated_at ON consents;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+
+DROP TABLE IF EXISTS researcher_profiles;
+DROP TABLE IF EXISTS consents;
+DROP TABLE IF EXISTS records;
+DROP TABLE IF EXISTS users;
@@ -1,9 +1,12 @@
+-- Baseline schema, formerly init.sql. Every statement is guarded so databases
+-- created from init.sql by hand can adopt the migration runner.
+
 -- 1. Enable the extension to generate random UUIDs (Standard in Postgres 13+)
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
 -- 2. Create the Users Table
 -- We store the wallet address to link identity to records.
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     wallet_address VARCHAR(42) UNIQUE NOT NULL, -- Standard ETH address length
     role VARCHAR(20) CHECK (role IN ('patient', 'researcher')) DEFAULT 'patient',
@@ -12,7 +15,7 @@ CREATE TABLE users (
 
 -- 3. Create the Medical Records Table
 -- This stores the 'directions' for Lit Protocol and IPFS.
-CREATE TABLE records (
+CREATE TABLE IF NOT EXISTS records (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     patient_id UUID NOT NULL,
     name VARCHAR(255) NOT NULL,               -- e.g., "MRI Scan - Dec 2025"
@@ -40,8 +43,8 @@ CREATE TABLE IF NOT EXISTS consents (
     CONSTRAINT unique_researcher_record UNIQUE(record_id, researcher_address)
 );
 
--- 2. Create a specific table for Researcher Metadata
-CREATE TABLE researcher_profiles (
+-- 4. Create a specific table for Researcher Metadata
+CREATE TABLE IF NOT EXISTS researcher_profiles (
     user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
     full_name VARCHAR(100) NOT NULL,
     institution VARCHAR(150) NOT NULL,
@@ -53,24 +56,24 @@ CREATE TABLE researcher_profiles (
 );
 
 -- Index for fast lookup by email or institution
-    CREATE INDEX idx_researcher_institution ON researcher_profiles(institution);
-
-    -- Indexes for the Researcher Portal
-    -- This makes "Show me all records I have access to" near-instant
-    CREATE INDEX idx_consents_researcher ON consents(researcher_address);
-    CREATE INDEX idx_consents_status ON consents(status);
+CREATE INDEX IF NOT EXISTS idx_researcher_institution ON researcher_profiles(institution);
 
-    -- Trigger to auto-update the updated_at column
-    CREATE OR REPLACE FUNCTION update_updated_at_column()
-    RETURNS TRIGGER AS $$
-    BEGIN
-        NEW.updated_at = CURRENT_TIMESTAMP;
-        RETURN NEW;
-    END;
-    $$ language 'plpgsql';
+-- Indexes for the Researcher Portal
+-- This makes "Show me all records I have access to" near-instant
+CREATE INDEX IF NOT EXISTS idx_consents_researcher ON consents(researcher_address);
+CREATE INDEX IF NOT EXISTS idx_consents_status ON consents(status);
 
-    CREATE TRIGGER update_consents_updated_at
-        BEFORE UPDATE ON consents
-        FOR EACH ROW
-        EXECUTE PROCEDURE update_updated_at_column();
+-- Trigger to auto-update the updated_at column
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_consents_updated_at ON consents;
+CREATE TRIGGER update_consents_updated_at
+    BEFORE UPDATE ON consents
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_updated_at_column();
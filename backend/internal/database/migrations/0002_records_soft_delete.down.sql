@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_records_active;
+
+ALTER TABLE records DROP COLUMN IF EXISTS deleted_at;
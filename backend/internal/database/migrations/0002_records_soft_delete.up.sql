@@ -0,0 +1,5 @@
+-- Soft delete for records. Deleted rows are hidden from the patient and
+-- researcher listings but kept so consent history stays auditable.
+ALTER TABLE records ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_records_active ON records(created_at DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+-- Last block fully processed by each chain listener stream, so events emitted
+-- while the backend was down can be backfilled on startup.
+CREATE TABLE IF NOT EXISTS indexer_cursors (
+    name VARCHAR(120) PRIMARY KEY,            -- contract address and event name
+    last_block BIGINT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
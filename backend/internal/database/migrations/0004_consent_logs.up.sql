@@ -0,0 +1,20 @@
+-- Every consent log applied by the chain listener, with the block it was seen
+-- in and the consent state it replaced, so logs dropped by a reorg can be
+-- rolled back and unconfirmed ones promoted once final.
+CREATE TABLE IF NOT EXISTS consent_logs (
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    record_id UUID NOT NULL,
+    researcher_address VARCHAR(42) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('granted', 'revoked')), -- Status once final
+    previous_status VARCHAR(20),                                          -- NULL when the consent did not exist
+    previous_tx_hash VARCHAR(66),
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (tx_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consent_logs_unconfirmed ON consent_logs(block_number, log_index) WHERE NOT confirmed;
@@ -0,0 +1,5 @@
+ALTER TABLE consent_logs DROP COLUMN IF EXISTS previous_log_index;
+ALTER TABLE consent_logs DROP COLUMN IF EXISTS previous_block_number;
+
+ALTER TABLE consents DROP COLUMN IF EXISTS last_log_index;
+ALTER TABLE consents DROP COLUMN IF EXISTS last_block_number;
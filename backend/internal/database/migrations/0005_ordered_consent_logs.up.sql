@@ -0,0 +1,18 @@
+-- Position of the log that last changed each consent, so an older log can
+-- never overwrite a newer state.
+ALTER TABLE consents ADD COLUMN IF NOT EXISTS last_block_number BIGINT;
+ALTER TABLE consents ADD COLUMN IF NOT EXISTS last_log_index INTEGER;
+
+ALTER TABLE consent_logs ADD COLUMN IF NOT EXISTS previous_block_number BIGINT;
+ALTER TABLE consent_logs ADD COLUMN IF NOT EXISTS previous_log_index INTEGER;
+
+-- The listener now reads one ordered stream per contract, resume it from the
+-- least advanced of the former per-event cursors.
+INSERT INTO indexer_cursors (name, last_block)
+SELECT split_part(name, ':', 1), MIN(last_block)
+FROM indexer_cursors
+WHERE name LIKE '%:%'
+GROUP BY split_part(name, ':', 1)
+ON CONFLICT (name) DO NOTHING;
+
+DELETE FROM indexer_cursors WHERE name LIKE '%:%';
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS consent_events_append_only ON consent_events;
+DROP FUNCTION IF EXISTS prevent_consent_event_rewrite();
+DROP TABLE IF EXISTS consent_events;
@@ -0,0 +1,47 @@
+-- Append-only audit trail of every consent event seen on-chain. Rows are
+-- never rewritten; a log dropped by a reorg is only flagged as removed so the
+-- history shows what the indexer saw.
+CREATE TABLE IF NOT EXISTS consent_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    record_id UUID NOT NULL,                  -- No FK, history outlives records
+    patient_address VARCHAR(42) NOT NULL,
+    researcher_address VARCHAR(42) NOT NULL,
+    event_type VARCHAR(40) NOT NULL,          -- Contract event name, e.g. ConsentGranted
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    block_timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    removed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_consent_event UNIQUE (tx_hash, log_index, block_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consent_events_record ON consent_events(record_id, block_number, log_index);
+CREATE INDEX IF NOT EXISTS idx_consent_events_patient ON consent_events(LOWER(patient_address));
+CREATE INDEX IF NOT EXISTS idx_consent_events_researcher ON consent_events(LOWER(researcher_address));
+
+CREATE OR REPLACE FUNCTION prevent_consent_event_rewrite()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        RAISE EXCEPTION 'consent_events is append-only';
+    END IF;
+    IF NEW.removed IS DISTINCT FROM OLD.removed
+        AND (NEW.record_id, NEW.patient_address, NEW.researcher_address, NEW.event_type,
+             NEW.tx_hash, NEW.log_index, NEW.block_number, NEW.block_hash, NEW.block_timestamp)
+        IS NOT DISTINCT FROM
+            (OLD.record_id, OLD.patient_address, OLD.researcher_address, OLD.event_type,
+             OLD.tx_hash, OLD.log_index, OLD.block_number, OLD.block_hash, OLD.block_timestamp) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'consent_events is append-only, only the removed flag may change';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS consent_events_append_only ON consent_events;
+CREATE TRIGGER consent_events_append_only
+    BEFORE UPDATE OR DELETE ON consent_events
+    FOR EACH ROW
+    EXECUTE PROCEDURE prevent_consent_event_rewrite();
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_records_registration_pending;
+DROP INDEX IF EXISTS idx_records_onchain_id_hash;
+
+ALTER TABLE records DROP COLUMN IF EXISTS registration_status;
+ALTER TABLE records DROP COLUMN IF EXISTS registration_block_hash;
+ALTER TABLE records DROP COLUMN IF EXISTS registration_block_number;
+ALTER TABLE records DROP COLUMN IF EXISTS registration_tx_hash;
+ALTER TABLE records DROP COLUMN IF EXISTS onchain_owner;
+ALTER TABLE records DROP COLUMN IF EXISTS onchain_id_hash;
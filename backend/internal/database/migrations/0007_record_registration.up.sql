@@ -0,0 +1,18 @@
+-- On-chain registration of each record. RecordRegistered indexes the record id
+-- as a string, so its log only carries keccak256(record id); records keep that
+-- hash to be matched against the log. Existing rows get it filled in by the
+-- chain listener on startup.
+ALTER TABLE records ADD COLUMN IF NOT EXISTS onchain_id_hash VARCHAR(66);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS onchain_owner VARCHAR(42);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS registration_tx_hash VARCHAR(66);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS registration_block_number BIGINT;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS registration_block_hash VARCHAR(66);
+
+-- pending: not seen on-chain yet, registered: on-chain owner matches the
+-- patient, owner_mismatch: registered by another wallet, unregistered: not
+-- registered within RECORD_REGISTRATION_WINDOW.
+ALTER TABLE records ADD COLUMN IF NOT EXISTS registration_status VARCHAR(20) NOT NULL DEFAULT 'pending'
+    CHECK (registration_status IN ('pending', 'registered', 'owner_mismatch', 'unregistered'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_records_onchain_id_hash ON records(onchain_id_hash);
+CREATE INDEX IF NOT EXISTS idx_records_registration_pending ON records(created_at) WHERE registration_status = 'pending';
@@ -0,0 +1,14 @@
+-- One row per ciphertext download served through the API, shown to the
+-- record's patient.
+CREATE TABLE IF NOT EXISTS record_access_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    record_id UUID NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    accessor_address VARCHAR(42) NOT NULL,
+    byte_range VARCHAR(100),                  -- Range header, NULL for full downloads
+    status_code INTEGER NOT NULL,
+    bytes_served BIGINT NOT NULL DEFAULT 0,
+    gateway TEXT NOT NULL,
+    accessed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_access_logs_record ON record_access_logs(record_id, accessed_at DESC);
@@ -0,0 +1,33 @@
+-- Researchers asking a patient for consent on a record. A request is pending
+-- until the patient answers, approved until the grant is seen on-chain, then
+-- fulfilled. Pending requests expire at expires_at.
+CREATE TABLE IF NOT EXISTS access_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    record_id UUID NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    patient_address VARCHAR(42) NOT NULL,
+    researcher_address VARCHAR(42) NOT NULL,
+    purpose TEXT NOT NULL,
+    duration_days INTEGER NOT NULL CHECK (duration_days > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'declined', 'fulfilled', 'expired')),
+    decline_reason TEXT,
+    fulfilled_tx_hash VARCHAR(66),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one open request per researcher and record
+CREATE UNIQUE INDEX IF NOT EXISTS idx_access_requests_open
+    ON access_requests(record_id, LOWER(researcher_address))
+    WHERE status IN ('pending', 'approved');
+
+CREATE INDEX IF NOT EXISTS idx_access_requests_patient ON access_requests(LOWER(patient_address), created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_access_requests_researcher ON access_requests(LOWER(researcher_address), created_at DESC);
+
+DROP TRIGGER IF EXISTS update_access_requests_updated_at ON access_requests;
+CREATE TRIGGER update_access_requests_updated_at
+    BEFORE UPDATE ON access_requests
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_updated_at_column();
@@ -0,0 +1,2 @@
+ALTER TABLE records DROP COLUMN IF EXISTS content_size;
+ALTER TABLE records DROP COLUMN IF EXISTS content_sha256;
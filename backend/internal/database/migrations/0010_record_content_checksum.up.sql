@@ -0,0 +1,5 @@
+-- SHA-256 and byte size of the uploaded ciphertext, computed while streaming
+-- the upload, so downloads can be checked against them. NULL for records
+-- uploaded before they were recorded.
+ALTER TABLE records ADD COLUMN IF NOT EXISTS content_sha256 VARCHAR(64);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS content_size BIGINT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deferred_chain_logs;
+DROP TABLE IF EXISTS upload_jobs;
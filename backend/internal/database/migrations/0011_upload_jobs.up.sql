@@ -0,0 +1,56 @@
+-- Record uploads accepted by POST /api/v1/records and processed by the upload
+-- workers. The ciphertext waits in staging_path until it is pinned.
+-- queued: waiting for a worker, pinning: being uploaded to storage, saving:
+-- pinned, the record is being inserted, completed / failed: final.
+CREATE TABLE IF NOT EXISTS upload_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    record_id UUID NOT NULL,
+    record_id_hash VARCHAR(66) NOT NULL,           -- matched against RecordRegistered logs
+    patient_address VARCHAR(42) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    data_to_encrypt_hash TEXT NOT NULL,
+    acc_json JSONB NOT NULL,
+    staging_path TEXT NOT NULL,
+    content_size BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'pinning', 'saving', 'completed', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    ipfs_cid TEXT,
+    content_sha256 VARCHAR(64),
+    error TEXT,                                    -- last failure, kept while retrying
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP, -- retry backoff
+    locked_until TIMESTAMP WITH TIME ZONE,        -- lease of the worker processing it
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_jobs_open ON upload_jobs(created_at)
+    WHERE status IN ('queued', 'pinning', 'saving');
+CREATE INDEX IF NOT EXISTS idx_upload_jobs_record_id_hash ON upload_jobs(record_id_hash);
+
+DROP TRIGGER IF EXISTS update_upload_jobs_updated_at ON upload_jobs;
+CREATE TRIGGER update_upload_jobs_updated_at
+    BEFORE UPDATE ON upload_jobs
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_updated_at_column();
+
+-- The patient registers the record on-chain, and may grant consents, while
+-- its upload is still open. Those logs wait here and are applied in the
+-- transaction that inserts the record.
+CREATE TABLE IF NOT EXISTS deferred_chain_logs (
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    record_id UUID,                  -- consent logs
+    record_id_hash VARCHAR(66),      -- registrations
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tx_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deferred_chain_logs_record ON deferred_chain_logs(record_id)
+    WHERE record_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_deferred_chain_logs_record_hash ON deferred_chain_logs(record_id_hash)
+    WHERE record_id_hash IS NOT NULL;
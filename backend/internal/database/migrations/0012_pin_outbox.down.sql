@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_records_ipfs_cid;
+DROP TABLE IF EXISTS pin_outbox;
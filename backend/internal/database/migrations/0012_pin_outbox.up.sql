@@ -0,0 +1,29 @@
+-- Pins an upload worker is about to create, recorded before the upload so
+-- content whose record is never saved can be found and unpinned.
+-- pending: pinned or being pinned, committed: the record was saved,
+-- unpinned: removed by the orphan sweeper, released: left pinned because
+-- another record uses the same content.
+CREATE TABLE IF NOT EXISTS pin_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cid TEXT NOT NULL,
+    upload_job_id UUID REFERENCES upload_jobs(id) ON DELETE SET NULL,
+    record_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'committed', 'unpinned', 'released')),
+    attempts INTEGER NOT NULL DEFAULT 0,          -- failed unpin attempts
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pin_outbox_pending ON pin_outbox(created_at)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_pin_outbox_cid ON pin_outbox(cid);
+CREATE INDEX IF NOT EXISTS idx_records_ipfs_cid ON records(ipfs_cid);
+
+DROP TRIGGER IF EXISTS update_pin_outbox_updated_at ON pin_outbox;
+CREATE TRIGGER update_pin_outbox_updated_at
+    BEFORE UPDATE ON pin_outbox
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_updated_at_column();
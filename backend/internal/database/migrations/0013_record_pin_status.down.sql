@@ -0,0 +1,3 @@
+ALTER TABLE records DROP COLUMN IF EXISTS pin_repinned_at;
+ALTER TABLE records DROP COLUMN IF EXISTS pin_checked_at;
+ALTER TABLE records DROP COLUMN IF EXISTS pin_status;
@@ -0,0 +1,8 @@
+-- Whether the record's ipfs_cid was still pinned by the storage backend at the
+-- last pin health check: unchecked, pinned, or missing when it was not pinned
+-- and could not be re-pinned. pin_repinned_at is the last time missing
+-- content was restored.
+ALTER TABLE records ADD COLUMN IF NOT EXISTS pin_status VARCHAR(20) NOT NULL DEFAULT 'unchecked'
+    CHECK (pin_status IN ('unchecked', 'pinned', 'missing'));
+ALTER TABLE records ADD COLUMN IF NOT EXISTS pin_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS pin_repinned_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,28 @@
+-- Per-provider pin state of each record's content when STORAGE_PROVIDERS
+-- replicates it. pending: not confirmed yet, pinned: the provider pins it,
+-- failed: the last attempt to replicate it there failed and will be retried.
+-- Rows for records created before replication, or for a provider added
+-- later, are backfilled by the replicator.
+CREATE TABLE IF NOT EXISTS record_pins (
+    record_id UUID NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    cid TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'pinned', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,          -- failed replication attempts
+    error TEXT,
+    pinned_at TIMESTAMP WITH TIME ZONE,
+    checked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (record_id, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_pins_unpinned ON record_pins(updated_at)
+    WHERE status <> 'pinned';
+
+DROP TRIGGER IF EXISTS update_record_pins_updated_at ON record_pins;
+CREATE TRIGGER update_record_pins_updated_at
+    BEFORE UPDATE ON record_pins
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_updated_at_column();
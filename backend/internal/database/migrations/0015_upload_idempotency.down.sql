@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_upload_jobs_record;
+DROP INDEX IF EXISTS idx_upload_jobs_idempotency_key;
+ALTER TABLE upload_jobs DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,27 @@
+-- Idempotent uploads: a patient's Idempotency-Key identifies one upload, and
+-- a record id has at most one upload that has not failed, so a retried
+-- request finds the upload it already queued instead of queuing another.
+ALTER TABLE upload_jobs ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upload_jobs_idempotency_key
+    ON upload_jobs(LOWER(patient_address), idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+
+-- Records queued more than once before this migration keep their completed
+-- upload, or else the oldest; the others would fail on the duplicate record.
+UPDATE upload_jobs
+SET status = 'failed', error = 'A record with this id already exists', locked_until = NULL, completed_at = NOW()
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY record_id ORDER BY status = 'completed' DESC, created_at
+        ) AS n
+        FROM upload_jobs
+        WHERE status <> 'failed'
+    ) ranked
+    WHERE n > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upload_jobs_record
+    ON upload_jobs(record_id)
+    WHERE status <> 'failed';
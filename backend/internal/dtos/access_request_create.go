@@ -0,0 +1,12 @@
+package dtos
+
+type AccessRequestCreateDto struct {
+	RecordID          string `json:"record_id"`
+	ResearcherAddress string `json:"researcher_address"`
+	Purpose           string `json:"purpose"`
+	DurationDays      int    `json:"duration_days"`
+}
+
+type AccessRequestDeclineDto struct {
+	Reason string `json:"reason"`
+}
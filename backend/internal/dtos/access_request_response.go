@@ -0,0 +1,27 @@
+package dtos
+
+import "time"
+
+type AccessRequestResponse struct {
+	ID                string     `json:"id"`
+	RecordID          string     `json:"record_id"`
+	RecordName        string     `json:"record_name"`
+	PatientAddress    string     `json:"patient_address"`
+	ResearcherAddress string     `json:"researcher_address"`
+	Purpose           string     `json:"purpose"`
+	DurationDays      int        `json:"duration_days"`
+	Status            string     `json:"status"`
+	DeclineReason     *string    `json:"decline_reason,omitempty"`
+	FulfilledTxHash   *string    `json:"fulfilled_tx_hash,omitempty"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RespondedAt       *time.Time `json:"responded_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// AccessRequestApproveResponse carries the grantConsent call the patient still
+// has to sign; the request is fulfilled once the indexer sees the grant.
+type AccessRequestApproveResponse struct {
+	Request      AccessRequestResponse `json:"request"`
+	GrantConsent UnsignedContractCall  `json:"grant_consent"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+import "time"
+
+type AuthSessionResponse struct {
+	Token     string    `json:"token"`
+	Address   string    `json:"address"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,6 @@
+package dtos
+
+type AuthVerifyRequest struct {
+	Message   string `json:"message"`   // The EIP-4361 message that was signed
+	Signature string `json:"signature"` // 0x-prefixed personal_sign signature
+}
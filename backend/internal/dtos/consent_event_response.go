@@ -0,0 +1,18 @@
+package dtos
+
+import "time"
+
+// ConsentEventResponse is one entry of the on-chain consent history. Removed
+// events were seen by the indexer but dropped from the chain by a reorg.
+type ConsentEventResponse struct {
+	RecordID          string    `json:"record_id"`
+	PatientAddress    string    `json:"patient_address"`
+	ResearcherAddress string    `json:"researcher_address"`
+	EventType         string    `json:"event_type"`
+	TxHash            string    `json:"tx_hash"`
+	LogIndex          uint      `json:"log_index"`
+	BlockNumber       uint64    `json:"block_number"`
+	BlockTimestamp    time.Time `json:"block_timestamp"`
+	Confirmed         bool      `json:"confirmed"`
+	Removed           bool      `json:"removed"`
+}
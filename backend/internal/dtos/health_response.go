@@ -0,0 +1,17 @@
+package dtos
+
+import "time"
+
+type HealthResponse struct {
+	Status     string                     `json:"status"` // "up", "degraded" or "down"
+	CheckedAt  time.Time                  `json:"checked_at"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status    string         `json:"status"` // "up" or "down"
+	Critical  bool           `json:"critical"`
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
@@ -0,0 +1,50 @@
+package dtos
+
+import "time"
+
+// PatientConsentOverviewResponse summarises every consent on a patient's
+// records, grouped both by record and by researcher.
+type PatientConsentOverviewResponse struct {
+	PatientAddress string                     `json:"patient_address"`
+	Totals         ConsentCounts              `json:"totals"`
+	Records        []RecordConsentSummary     `json:"records"`
+	Researchers    []ResearcherConsentSummary `json:"researchers"`
+}
+
+type ConsentCounts struct {
+	Granted int `json:"granted"`
+	Revoked int `json:"revoked"`
+	Pending int `json:"pending"`
+}
+
+type RecordConsentSummary struct {
+	RecordID      string              `json:"record_id"`
+	RecordName    string              `json:"record_name"`
+	Counts        ConsentCounts       `json:"counts"`
+	LastChangedAt *time.Time          `json:"last_changed_at"`
+	Researchers   []ResearcherConsent `json:"researchers"`
+}
+
+type ResearcherConsent struct {
+	ResearcherAddress string    `json:"researcher_address"`
+	FullName          string    `json:"full_name,omitempty"`
+	Institution       string    `json:"institution,omitempty"`
+	Status            string    `json:"status"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type ResearcherConsentSummary struct {
+	ResearcherAddress string          `json:"researcher_address"`
+	FullName          string          `json:"full_name,omitempty"`
+	Institution       string          `json:"institution,omitempty"`
+	Counts            ConsentCounts   `json:"counts"`
+	LastChangedAt     time.Time       `json:"last_changed_at"`
+	Records           []RecordConsent `json:"records"`
+}
+
+type RecordConsent struct {
+	RecordID   string    `json:"record_id"`
+	RecordName string    `json:"record_name"`
+	Status     string    `json:"status"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,11 @@
+package dtos
+
+import "time"
+
+type RecordAccessLogResponse struct {
+	AccessorAddress string    `json:"accessor_address"`
+	ByteRange       *string   `json:"byte_range,omitempty"`
+	StatusCode      int       `json:"status_code"`
+	BytesServed     int64     `json:"bytes_served"`
+	AccessedAt      time.Time `json:"accessed_at"`
+}
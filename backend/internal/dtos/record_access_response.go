@@ -0,0 +1,21 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RecordAccessResponse is the material a researcher needs to fetch and
+// decrypt a record, returned once the contract confirms their access.
+type RecordAccessResponse struct {
+	Id                string          `json:"id"`
+	Name              string          `json:"name"`
+	IPFSCid           string          `json:"ipfs_cid"`
+	DataToEncryptHash string          `json:"data_to_encrypt_hash"`
+	AccJson           json.RawMessage `json:"acc_json"`
+	PatientAddress    string          `json:"patient_address"`
+	ContentSHA256     *string         `json:"content_sha256,omitempty"`
+	ContentSize       *int64          `json:"content_size,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	VerifiedAt        time.Time       `json:"verified_at"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+// RecordCreatedResponse is the answer to a record upload saved before uploads
+// were queued, replayed when such an upload is retried.
+type RecordCreatedResponse struct {
+	Message  string `json:"message"`
+	Cid      string `json:"cid"`
+	RecordID string `json:"record_id"`
+}
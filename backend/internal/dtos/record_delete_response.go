@@ -0,0 +1,14 @@
+package dtos
+
+type RecordDeleteResponse struct {
+	Message        string                 `json:"message"`
+	RecordID       string                 `json:"record_id"`
+	PendingRevokes []UnsignedContractCall `json:"pending_revocations"`
+}
+
+// UnsignedContractCall is a transaction the wallet still has to sign and send.
+type UnsignedContractCall struct {
+	ResearcherAddress string `json:"researcher_address"`
+	To                string `json:"to"`
+	Data              string `json:"data"`
+}
@@ -0,0 +1,29 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RecordDetailResponse is a single record with its consents. Decryption
+// material and the owner are only populated for the owner and for researchers
+// holding a granted consent.
+type RecordDetailResponse struct {
+	Id                string                  `json:"id"`
+	Name              string                  `json:"name"`
+	IPFSCid           string                  `json:"ipfs_cid,omitempty"`
+	DataToEncryptHash string                  `json:"data_to_encrypt_hash,omitempty"`
+	AccJson           json.RawMessage         `json:"acc_json,omitempty"`
+	PatientAddress    string                  `json:"patient_address,omitempty"`
+	ContentSHA256     *string                 `json:"content_sha256,omitempty"`
+	ContentSize       *int64                  `json:"content_size,omitempty"`
+	CreatedAt         time.Time               `json:"created_at"`
+	Consents          []RecordConsentResponse `json:"consents"`
+}
+
+type RecordConsentResponse struct {
+	ResearcherAddress string    `json:"researcher_address"`
+	Status            string    `json:"status"`
+	LastTxHash        string    `json:"last_tx_hash"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
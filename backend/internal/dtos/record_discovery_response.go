@@ -0,0 +1,14 @@
+package dtos
+
+import "time"
+
+// RecordDiscoveryResponse is the researcher-facing view of a record the
+// researcher has not been granted access to. It deliberately omits the CID,
+// access control conditions and owner.
+type RecordDiscoveryResponse struct {
+	Id                 string     `json:"id"`
+	Name               string     `json:"name"`
+	CreatedAt          time.Time  `json:"created_at"`
+	ConsentStatus      string     `json:"consent_status"`
+	LastUpdatedConsent *time.Time `json:"last_updated_consent"`
+}
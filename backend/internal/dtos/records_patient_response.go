@@ -12,5 +12,27 @@ type RecordsByPatientResponse struct {
 	DataToEncryptHash string          `json:"data_to_encrypt_hash"`
 	AccJson           json.RawMessage `json:"acc_json"`
 	PatientAddress    string          `json:"patient_address"`
+	ContentSHA256     *string         `json:"content_sha256,omitempty"` // NULL for records uploaded before checksums
+	ContentSize       *int64          `json:"content_size,omitempty"`
 	CreatedAt         time.Time       `json:"created_at"`
+	DeletedAt         *time.Time      `json:"deleted_at,omitempty"`
+	Registration      Registration    `json:"registration"`
+	Pin               PinHealth       `json:"pin"`
+}
+
+// Registration is the on-chain registration of a record as seen by the
+// indexer. Status is pending, registered, owner_mismatch or unregistered.
+type Registration struct {
+	Status      string  `json:"status"`
+	Owner       *string `json:"owner,omitempty"`
+	TxHash      *string `json:"tx_hash,omitempty"`
+	BlockNumber *int64  `json:"block_number,omitempty"`
+}
+
+// PinHealth is the state of a record's content at the last pin health check.
+// Status is unchecked, pinned or missing.
+type PinHealth struct {
+	Status     string     `json:"status"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+	RepinnedAt *time.Time `json:"repinned_at,omitempty"`
 }
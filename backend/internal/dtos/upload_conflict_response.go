@@ -0,0 +1,11 @@
+package dtos
+
+// UploadConflictResponse is the 409 answer to an upload that conflicts with
+// an earlier one. Error is record_exists or idempotency_key_reused; UploadID
+// is the earlier upload, when it is the caller's.
+type UploadConflictResponse struct {
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	RecordID string `json:"record_id"`
+	UploadID string `json:"upload_id,omitempty"`
+}
@@ -0,0 +1,20 @@
+package dtos
+
+import "time"
+
+// UploadJobResponse is the progress of a record upload. Status is queued,
+// pinning, saving, completed or failed; Error holds the reason of the last
+// failed attempt, kept while the job is retried.
+type UploadJobResponse struct {
+	Id            string     `json:"id"`
+	RecordId      string     `json:"record_id"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	ContentSize   int64      `json:"content_size"`
+	IPFSCid       *string    `json:"ipfs_cid,omitempty"`
+	ContentSHA256 *string    `json:"content_sha256,omitempty"`
+	Error         *string    `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAccessRequestTTL        = 7 * 24 * time.Hour
+	defaultAccessRequestRateLimit  = 3
+	defaultAccessRequestRateWindow = 24 * time.Hour
+)
+
+func StartAccessRequestsHandler(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/access-requests", createAccessRequest)
+	mux.HandleFunc("GET /api/v1/access-requests/patient/{address}", getPatientAccessRequests)
+	mux.HandleFunc("GET /api/v1/access-requests/researcher/{address}", getResearcherAccessRequests)
+	mux.HandleFunc("POST /api/v1/access-requests/{id}/approve", approveAccessRequest)
+	mux.HandleFunc("POST /api/v1/access-requests/{id}/decline", declineAccessRequest)
+}
+
+// createAccessRequest lets a researcher ask the owner of a record for consent.
+// Requests are limited per researcher and patient pair.
+func createAccessRequest(w http.ResponseWriter, r *http.Request) {
+	var request dtos.AccessRequestCreateDto
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		log.Printf("Error decoding request body: %v", err)
+		return
+	}
+
+	if err := helpers.ValidateAccessRequest(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Printf("Bad Request: %v", err)
+		return
+	}
+
+	if !authorizeWallet(w, r, request.ResearcherAddress) {
+		return
+	}
+
+	record, err := repositories.GetRecordByID(request.RecordID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record: %v", err)
+		return
+	}
+
+	if record == nil {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+
+	if auth.SameAddress(record.PatientAddress, request.ResearcherAddress) {
+		http.Error(w, "Cannot request access to your own record", http.StatusBadRequest)
+		return
+	}
+
+	consents, err := repositories.GetConsentsByRecordID(request.RecordID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		log.Printf("Error retrieving consents: %v", err)
+		return
+	}
+	for _, consent := range consents {
+		if auth.SameAddress(consent.ResearcherAddress, request.ResearcherAddress) && consent.Status == "granted" {
+			http.Error(w, "Consent already granted", http.StatusConflict)
+			return
+		}
+	}
+
+	limit, window := getAccessRequestRateLimit()
+	created, err := repositories.CreateAccessRequest(models.AccessRequest{
+		RecordID:          request.RecordID,
+		PatientAddress:    record.PatientAddress,
+		ResearcherAddress: request.ResearcherAddress,
+		Purpose:           strings.TrimSpace(request.Purpose),
+		DurationDays:      request.DurationDays,
+		ExpiresAt:         time.Now().Add(getAccessRequestTTL()),
+	}, limit, time.Now().Add(-window))
+	if err != nil {
+		var limited *repositories.AccessRequestLimitError
+		if errors.As(err, &limited) {
+			retryAfter := math.Ceil(time.Until(limited.Oldest.Add(window)).Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(retryAfter), 1)))
+			http.Error(w, "Too many access requests to this patient", http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, repositories.ErrOpenAccessRequest) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create access request", http.StatusInternalServerError)
+		log.Printf("Error creating access request: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+func getPatientAccessRequests(w http.ResponseWriter, r *http.Request) {
+	listAccessRequests(w, r, repositories.GetAccessRequestsByPatient)
+}
+
+func getResearcherAccessRequests(w http.ResponseWriter, r *http.Request) {
+	listAccessRequests(w, r, repositories.GetAccessRequestsByResearcher)
+}
+
+// listAccessRequests serves the request list of the wallet in the path,
+// optionally filtered with ?status=.
+func listAccessRequests(w http.ResponseWriter, r *http.Request, list func(address string, status string) ([]dtos.AccessRequestResponse, error)) {
+	address := r.PathValue("address")
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		http.Error(w, "Invalid Ethereum address format", http.StatusBadRequest)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if !isValidAccessRequestStatus(status) {
+		http.Error(w, "Invalid status filter", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
+	requests, err := list(address, status)
+	if err != nil {
+		http.Error(w, "Failed to retrieve access requests", http.StatusInternalServerError)
+		log.Printf("Error retrieving access requests: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(requests); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// approveAccessRequest records the patient's approval and returns the
+// grantConsent call to sign. The request is fulfilled once the grant is
+// indexed.
+func approveAccessRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := loadOwnAccessRequest(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := helpers.BuildGrantConsentCalldata(request.ResearcherAddress, request.RecordID)
+	if err != nil {
+		http.Error(w, "Failed to encode grantConsent call", http.StatusInternalServerError)
+		log.Printf("Error encoding grantConsent calldata: %v", err)
+		return
+	}
+
+	approved, err := repositories.RespondToAccessRequest(request.ID, "approved", "")
+	if !respondedToAccessRequest(w, err) {
+		return
+	}
+
+	response := dtos.AccessRequestApproveResponse{
+		Request: *approved,
+		GrantConsent: dtos.UnsignedContractCall{
+			ResearcherAddress: approved.ResearcherAddress,
+			To:                os.Getenv("CONTRACT_ADDRESS"),
+			Data:              data,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// declineAccessRequest closes a pending request, with an optional reason.
+func declineAccessRequest(w http.ResponseWriter, r *http.Request) {
+	var decision dtos.AccessRequestDeclineDto
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		log.Printf("Error decoding request body: %v", err)
+		return
+	}
+
+	reason := strings.TrimSpace(decision.Reason)
+	if len(reason) > helpers.MaxAccessRequestPurposeLength {
+		http.Error(w, "Reason is too long", http.StatusBadRequest)
+		return
+	}
+
+	request, ok := loadOwnAccessRequest(w, r)
+	if !ok {
+		return
+	}
+
+	declined, err := repositories.RespondToAccessRequest(request.ID, "declined", reason)
+	if !respondedToAccessRequest(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(declined); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// loadOwnAccessRequest loads the request in the path and checks it was sent
+// to the signed-in patient.
+func loadOwnAccessRequest(w http.ResponseWriter, r *http.Request) (*dtos.AccessRequestResponse, bool) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid access request id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if _, ok := auth.WalletFromContext(r.Context()); !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	request, err := repositories.GetAccessRequestByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve access request", http.StatusInternalServerError)
+		log.Printf("Error retrieving access request: %v", err)
+		return nil, false
+	}
+
+	if request == nil {
+		http.Error(w, "Access request not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	if !authorizeWallet(w, r, request.PatientAddress) {
+		return nil, false
+	}
+
+	return request, true
+}
+
+func respondedToAccessRequest(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, repositories.ErrAccessRequestNotPending) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return false
+	}
+	http.Error(w, "Failed to update access request", http.StatusInternalServerError)
+	log.Printf("Error updating access request: %v", err)
+	return false
+}
+
+func isValidAccessRequestStatus(status string) bool {
+	switch status {
+	case "", "pending", "approved", "declined", "fulfilled", "expired":
+		return true
+	}
+	return false
+}
+
+func getAccessRequestTTL() time.Duration {
+	return getEnvDuration("ACCESS_REQUEST_TTL", defaultAccessRequestTTL)
+}
+
+// getAccessRequestRateLimit returns how many requests a researcher may send to
+// one patient per window.
+func getAccessRequestRateLimit() (int, time.Duration) {
+	limit := defaultAccessRequestRateLimit
+	if value := os.Getenv("ACCESS_REQUEST_RATE_LIMIT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			log.Printf("Invalid ACCESS_REQUEST_RATE_LIMIT %q, using %d", value, defaultAccessRequestRateLimit)
+		} else {
+			limit = parsed
+		}
+	}
+	return limit, getEnvDuration("ACCESS_REQUEST_RATE_WINDOW", defaultAccessRequestRateWindow)
+}
+
+func getEnvDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAccessRequest_Validation(t *testing.T) {
+	researcher := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	validBody := `{"record_id":"550e8400-e29b-41d4-a716-446655440000","researcher_address":"` + researcher + `","purpose":"Study","duration_days":30}`
+
+	tests := []struct {
+		name         string
+		body         string
+		wallet       string
+		expectedCode int
+	}{
+		{"Invalid JSON", `{`, researcher, http.StatusBadRequest},
+		{"Missing purpose", `{"record_id":"550e8400-e29b-41d4-a716-446655440000","researcher_address":"` + researcher + `","duration_days":30}`, researcher, http.StatusBadRequest},
+		{"Unauthenticated", validBody, "", http.StatusUnauthorized},
+		{"Wallet mismatch", validBody, "0x0000000000000000000000000000000000000001", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/access-requests", strings.NewReader(tt.body))
+			if tt.wallet != "" {
+				req = req.WithContext(auth.WithWallet(req.Context(), tt.wallet))
+			}
+			w := httptest.NewRecorder()
+
+			createAccessRequest(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestListAccessRequests_Validation(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+
+	tests := []struct {
+		name         string
+		address      string
+		status       string
+		wallet       string
+		expectedCode int
+	}{
+		{"Invalid address", "0x123", "", address, http.StatusBadRequest},
+		{"Invalid status", address, "granted", address, http.StatusBadRequest},
+		{"Unauthenticated", address, "pending", "", http.StatusUnauthorized},
+		{"Wallet mismatch", address, "", "0x0000000000000000000000000000000000000001", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/access-requests/patient/"+tt.address+"?status="+tt.status, nil)
+			req.SetPathValue("address", tt.address)
+			if tt.wallet != "" {
+				req = req.WithContext(auth.WithWallet(req.Context(), tt.wallet))
+			}
+			w := httptest.NewRecorder()
+
+			getPatientAccessRequests(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestRespondToAccessRequest_Validation(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"approve": approveAccessRequest,
+		"decline": declineAccessRequest,
+	}
+
+	for name, handler := range handlers {
+		t.Run(name+" invalid id", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/access-requests/not-a-uuid/"+name, nil)
+			req.SetPathValue("id", "not-a-uuid")
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+
+		t.Run(name+" unauthenticated", func(t *testing.T) {
+			id := "550e8400-e29b-41d4-a716-446655440000"
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/access-requests/"+id+"/"+name, nil)
+			req.SetPathValue("id", id)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestGetAccessRequestRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      string
+		window     string
+		wantLimit  int
+		wantWindow time.Duration
+	}{
+		{"Defaults", "", "", defaultAccessRequestRateLimit, defaultAccessRequestRateWindow},
+		{"Configured", "10", "1h", 10, time.Hour},
+		{"Invalid values", "zero", "-1h", defaultAccessRequestRateLimit, defaultAccessRequestRateWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ACCESS_REQUEST_RATE_LIMIT", tt.limit)
+			t.Setenv("ACCESS_REQUEST_RATE_WINDOW", tt.window)
+
+			limit, window := getAccessRequestRateLimit()
+			if limit != tt.wantLimit || window != tt.wantWindow {
+				t.Errorf("getAccessRequestRateLimit() = (%d, %v), want (%d, %v)", limit, window, tt.wantLimit, tt.wantWindow)
+			}
+		})
+	}
+}
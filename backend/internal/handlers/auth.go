@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"consentis-api/internal/dtos"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func StartAuthHandler(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/auth/nonce", getNonce)
+	mux.HandleFunc("POST /api/v1/auth/verify", verifySiwe)
+}
+
+func getNonce(w http.ResponseWriter, r *http.Request) {
+	nonce, err := auth.GetNonceStore().Issue(clientAddress(r))
+	if errors.Is(err, auth.ErrNonceRateLimited) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(auth.NonceRateWindow.Seconds())))
+		http.Error(w, "Too many nonce requests", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, auth.ErrNonceStoreFull) {
+		http.Error(w, "Too many sign-in attempts, try again later", http.StatusServiceUnavailable)
+		log.Printf("Error generating nonce: %v", err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to generate nonce", http.StatusInternalServerError)
+		log.Printf("Error generating nonce: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"nonce": nonce}); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// clientAddress returns the IP address the request came from. Behind a
+// reverse proxy every connection comes from the proxy, so TRUSTED_PROXY_HEADER
+// can name the header it sets to the client's address, such as X-Real-IP or
+// X-Forwarded-For. Of a list the last entry is used, the one the proxy
+// appended; earlier ones are whatever the client sent. Without the setting,
+// or without a valid address in the header, the connection's address is used.
+func clientAddress(r *http.Request) string {
+	if header := os.Getenv("TRUSTED_PROXY_HEADER"); header != "" {
+		if values := r.Header.Values(header); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func verifySiwe(w http.ResponseWriter, r *http.Request) {
+	var request dtos.AuthVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		log.Printf("Error decoding request body: %v", err)
+		return
+	}
+
+	message, err := auth.ParseSiweMessage(request.Message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Printf("Bad Request: %v", err)
+		return
+	}
+
+	if err := message.Validate(getSiweDomain(), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	signer, err := auth.RecoverSigner(request.Message, request.Signature)
+	if err != nil {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		log.Printf("Error recovering signer: %v", err)
+		return
+	}
+
+	if !auth.SameAddress(signer.Hex(), message.Address) {
+		http.Error(w, "Signature does not match address", http.StatusUnauthorized)
+		return
+	}
+
+	// Consume the nonce only after the signature checks out so a bad attempt
+	// does not burn a nonce belonging to the real wallet owner.
+	if !auth.GetNonceStore().Consume(message.Nonce) {
+		http.Error(w, "Invalid or expired nonce", http.StatusUnauthorized)
+		return
+	}
+
+	token, expiresAt, err := auth.GetSessionManager().Issue(signer.Hex())
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		log.Printf("Error issuing session token: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	response := dtos.AuthSessionResponse{
+		Token:     token,
+		Address:   signer.Hex(),
+		ExpiresAt: expiresAt,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// WithAuth binds the wallet from a valid bearer session token to the request
+// context. Requests without a token pass through unauthenticated so public
+// routes keep working; handlers that need a wallet call authorizeWallet.
+func WithAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		session, err := auth.GetSessionManager().Verify(strings.TrimSpace(token))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithWallet(r.Context(), session.Address)))
+	})
+}
+
+// authorizeWallet writes a 401 when the caller is not signed in and a 403 when
+// the signed-in wallet is not the address the request acts on.
+func authorizeWallet(w http.ResponseWriter, r *http.Request, address string) bool {
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return false
+	}
+
+	if !auth.SameAddress(wallet, address) {
+		http.Error(w, "Address does not match authenticated wallet", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func getSiweDomain() string {
+	if domain := os.Getenv("SIWE_DOMAIN"); domain != "" {
+		return domain
+	}
+
+	origin := os.Getenv("ALLOWED_ORIGIN")
+	if origin == "" {
+		origin = "http://localhost:3000"
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return origin
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"consentis-api/internal/auth"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func signSiwe(t *testing.T, domain string, nonce string) (string, string, string) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	message := fmt.Sprintf("%s wants you to sign in with your Ethereum account:\n%s\n\nSign in to Consentis.\n\nURI: http://%s\nVersion: 1\nChain ID: 11155111\nNonce: %s\nIssued At: %s",
+		domain, address, domain, nonce, time.Now().UTC().Format(time.RFC3339))
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("sign message: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	return message, hexutil.Encode(sig), address
+}
+
+func TestVerifySiwe_IssuesSession(t *testing.T) {
+	t.Setenv("SIWE_DOMAIN", "localhost:3000")
+
+	nonce, err := auth.GetNonceStore().Issue("192.0.2.1")
+	if err != nil {
+		t.Fatalf("issue nonce: %v", err)
+	}
+	message, signature, address := signSiwe(t, "localhost:3000", nonce)
+
+	body, _ := json.Marshal(map[string]string{"message": message, "signature": signature})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	verifySiwe(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Token   string `json:"token"`
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Address != address {
+		t.Errorf("Expected address %s, got %s", address, response.Address)
+	}
+
+	// The nonce is single use
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	verifySiwe(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 on nonce replay, got %d", w.Code)
+	}
+}
+
+func TestVerifySiwe_WrongDomain(t *testing.T) {
+	t.Setenv("SIWE_DOMAIN", "localhost:3000")
+
+	nonce, _ := auth.GetNonceStore().Issue("192.0.2.1")
+	message, signature, _ := signSiwe(t, "evil.example", nonce)
+
+	body, _ := json.Marshal(map[string]string{"message": message, "signature": signature})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	verifySiwe(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestWithAuth(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	token, _, err := auth.GetSessionManager().Issue(address)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantWallet string
+	}{
+		{"No header", "", http.StatusOK, ""},
+		{"Valid token", "Bearer " + token, http.StatusOK, address},
+		{"Tampered token", "Bearer " + token + "x", http.StatusUnauthorized, ""},
+		{"Wrong scheme", "Basic " + token, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotWallet string
+			handler := WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotWallet, _ = auth.WalletFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if !strings.EqualFold(gotWallet, tt.wantWallet) {
+				t.Errorf("Expected wallet %q, got %q", tt.wantWallet, gotWallet)
+			}
+		})
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		want   string
+	}{
+		{"Header not trusted", "", []string{"203.0.113.7"}, "192.0.2.1"},
+		{"Real IP", "X-Real-IP", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"Last forwarded entry", "X-Forwarded-For", []string{"198.51.100.9, 203.0.113.7"}, "203.0.113.7"},
+		{"Last forwarded header", "X-Forwarded-For", []string{"198.51.100.9", "203.0.113.7"}, "203.0.113.7"},
+		{"IPv6", "X-Forwarded-For", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"Invalid entry", "X-Forwarded-For", []string{"203.0.113.7, unknown"}, "192.0.2.1"},
+		{"Missing header", "X-Forwarded-For", nil, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXY_HEADER", tt.header)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/nonce", nil)
+			req.RemoteAddr = "192.0.2.1:51234"
+			for _, value := range tt.values {
+				req.Header.Add("X-Forwarded-For", value)
+				req.Header.Add("X-Real-IP", value)
+			}
+
+			if got := clientAddress(req); got != tt.want {
+				t.Errorf("clientAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,13 +17,19 @@ func NewServer(addr string) *Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", homePage)
 
+	StartHealthHandler(mux)
+	StartAuthHandler(mux)
 	StartRecordsHandler(mux)
+	StartConsentsHandler(mux)
+	StartAccessRequestsHandler(mux)
 	StartResearchersHandler(mux)
+	StartUploadsHandler(mux)
+	StartMetricsHandler(mux)
 
 	return &Server{
 		httpServer: &http.Server{
 			Addr:         addr,
-			Handler:      WithCORS(mux),
+			Handler:      WithCORS(WithAuth(mux)),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
@@ -57,8 +63,9 @@ func WithCORS(next http.Handler) http.Handler {
 		}
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "Location, Idempotent-Replayed")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewServer panics when two routes conflict, so building it is enough to
+// catch overlapping patterns.
+func TestNewServer(t *testing.T) {
+	server := NewServer(":0")
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"Patient records", "/api/v1/records/patient/0x123", "Invalid Ethereum address format\n"},
+		{"Researcher records", "/api/v1/records/researcher/0x123", "Invalid Ethereum address format\n"},
+		{"Record access", "/api/v1/record-access/not-a-uuid", "Invalid record id\n"},
+		{"Record content", "/api/v1/record-content/not-a-uuid", "Invalid record id\n"},
+		{"Record access logs", "/api/v1/record-access-logs/not-a-uuid", "Invalid record id\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			server.httpServer.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest || w.Body.String() != tt.want {
+				t.Errorf("Expected 400 %q, got %d %q", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/repositories"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func StartConsentsHandler(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/records/{id}/consents/history", getRecordConsentHistory)
+	mux.HandleFunc("GET /api/v1/users/{address}/consents/history", getUserConsentHistory)
+}
+
+// getRecordConsentHistory returns every consent event of a record. The owner
+// sees the full history, any other wallet only the events naming it as the
+// researcher. Events dropped by a reorg are included with ?include_removed=true.
+func getRecordConsentHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	owner, found, err := repositories.GetRecordOwnerAddress(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record owner: %v", err)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+
+	researcher := ""
+	if !auth.SameAddress(owner, wallet) {
+		researcher = wallet
+	}
+
+	includeRemoved := r.URL.Query().Get("include_removed") == "true"
+	events, err := repositories.GetConsentHistoryByRecordID(id, researcher, includeRemoved)
+	if err != nil {
+		http.Error(w, "Failed to retrieve consent history", http.StatusInternalServerError)
+		log.Printf("Error retrieving consent history: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// getUserConsentHistory returns the consent events where the signed-in wallet
+// is either the patient or the researcher.
+func getUserConsentHistory(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		http.Error(w, "Invalid Ethereum address format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
+	includeRemoved := r.URL.Query().Get("include_removed") == "true"
+	events, err := repositories.GetConsentHistoryByAddress(address, includeRemoved)
+	if err != nil {
+		http.Error(w, "Failed to retrieve consent history", http.StatusInternalServerError)
+		log.Printf("Error retrieving consent history: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
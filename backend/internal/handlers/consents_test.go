@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRecordConsentHistory_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/not-a-uuid/consents/history", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	getRecordConsentHistory(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetRecordConsentHistory_Unauthenticated(t *testing.T) {
+	id := "550e8400-e29b-41d4-a716-446655440000"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/"+id+"/consents/history", nil)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+
+	getRecordConsentHistory(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestGetUserConsentHistory(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+
+	tests := []struct {
+		name         string
+		address      string
+		wallet       string
+		expectedCode int
+	}{
+		{"Invalid address", "0x123", address, http.StatusBadRequest},
+		{"Unauthenticated", address, "", http.StatusUnauthorized},
+		{"Wallet mismatch", address, "0x0000000000000000000000000000000000000001", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+tt.address+"/consents/history", nil)
+			req.SetPathValue("address", tt.address)
+			if tt.wallet != "" {
+				req = req.WithContext(auth.WithWallet(req.Context(), tt.wallet))
+			}
+			w := httptest.NewRecorder()
+
+			getUserConsentHistory(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	chainlistener "consentis-api/internal/chain-listener"
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const healthProbeTimeout = 3 * time.Second
+
+type healthCheck struct {
+	name     string
+	critical bool
+	probe    func(ctx context.Context) (map[string]any, error)
+}
+
+func StartHealthHandler(mux *http.ServeMux) {
+	mux.HandleFunc("GET /health", getHealth)
+	mux.HandleFunc("GET /ready", getReadiness)
+}
+
+// getHealth is the liveness probe. It checks no dependency, only that the
+// process serves requests, so an outage of the database, RPC node or storage
+// makes the API unready instead of getting the container restarted.
+func getHealth(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, runHealthChecks(r.Context(), nil))
+}
+
+// getReadiness reports every dependency the API needs to serve fresh data.
+func getReadiness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, runHealthChecks(r.Context(), []healthCheck{
+		databaseCheck(),
+		chainListenerCheck(),
+		storageCheck(),
+	}))
+}
+
+func databaseCheck() healthCheck {
+	return healthCheck{
+		name:     "database",
+		critical: true,
+		probe: func(ctx context.Context) (map[string]any, error) {
+			return nil, repositories.PingDB(ctx)
+		},
+	}
+}
+
+func chainListenerCheck() healthCheck {
+	return healthCheck{
+		name:     "chain_listener",
+		critical: true,
+		probe: func(ctx context.Context) (map[string]any, error) {
+			status := chainlistener.GetStatus()
+			details := map[string]any{
+				"state":         status.State,
+				"reconnects":    status.Reconnects,
+				"subscriptions": status.Subscriptions,
+				"last_block":    status.LastBlock,
+			}
+			if !status.ConnectedAt.IsZero() {
+				details["connected_at"] = status.ConnectedAt
+			}
+			if !status.LastEventAt.IsZero() {
+				details["last_event_at"] = status.LastEventAt
+			}
+
+			if !status.Subscribed {
+				if status.LastError != "" {
+					return details, fmt.Errorf("listener %s: %s", status.State, status.LastError)
+				}
+				return details, fmt.Errorf("listener %s", status.State)
+			}
+			return details, nil
+		},
+	}
+}
+
+func storageCheck() healthCheck {
+	return healthCheck{
+		name:     "storage",
+		critical: false,
+		probe: func(ctx context.Context) (map[string]any, error) {
+			storage, err := ipfs.GetStorage()
+			if err != nil {
+				return nil, err
+			}
+			return nil, storage.Ping(ctx)
+		},
+	}
+}
+
+// runHealthChecks probes every component concurrently. The overall status is
+// "down" when a critical component fails and "degraded" when only optional
+// ones do.
+func runHealthChecks(ctx context.Context, checks []healthCheck) dtos.HealthResponse {
+	response := dtos.HealthResponse{
+		Status:     "up",
+		CheckedAt:  time.Now().UTC(),
+		Components: make(map[string]dtos.ComponentHealth, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check.probe(probeCtx)
+			component := dtos.ComponentHealth{
+				Status:    "up",
+				Critical:  check.critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Details:   details,
+			}
+			if err != nil {
+				component.Status = "down"
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Components[check.name] = component
+		}(check)
+	}
+	wg.Wait()
+
+	for _, component := range response.Components {
+		if component.Status == "up" {
+			continue
+		}
+		if component.Critical {
+			response.Status = "down"
+			break
+		}
+		response.Status = "degraded"
+	}
+
+	return response
+}
+
+func writeHealth(w http.ResponseWriter, response dtos.HealthResponse) {
+	statusCode := http.StatusOK
+	if response.Status == "down" {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
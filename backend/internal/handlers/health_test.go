@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func staticCheck(name string, critical bool, err error) healthCheck {
+	return healthCheck{
+		name:     name,
+		critical: critical,
+		probe: func(ctx context.Context) (map[string]any, error) {
+			return nil, err
+		},
+	}
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []healthCheck
+		wantStatus string
+		wantCode   int
+	}{
+		{
+			name:       "All up",
+			checks:     []healthCheck{staticCheck("database", true, nil), staticCheck("pinata", false, nil)},
+			wantStatus: "up",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "Optional down",
+			checks:     []healthCheck{staticCheck("database", true, nil), staticCheck("pinata", false, errors.New("timeout"))},
+			wantStatus: "degraded",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "Critical down",
+			checks:     []healthCheck{staticCheck("database", true, errors.New("refused")), staticCheck("pinata", false, errors.New("timeout"))},
+			wantStatus: "down",
+			wantCode:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := runHealthChecks(context.Background(), tt.checks)
+			if response.Status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s", tt.wantStatus, response.Status)
+			}
+			if len(response.Components) != len(tt.checks) {
+				t.Errorf("Expected %d components, got %d", len(tt.checks), len(response.Components))
+			}
+
+			w := httptest.NewRecorder()
+			writeHealth(w, response)
+			if w.Code != tt.wantCode {
+				t.Errorf("Expected HTTP %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
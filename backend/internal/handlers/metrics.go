@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"consentis-api/internal/pins"
+	"consentis-api/internal/repositories"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+func StartMetricsHandler(mux *http.ServeMux) {
+	mux.HandleFunc("GET /metrics", getMetrics)
+}
+
+// getMetrics serves pin health metrics in the Prometheus text format. Record
+// counts come from the database, so every replica reports the same totals;
+// check and re-pin counters are those of this process.
+func getMetrics(w http.ResponseWriter, r *http.Request) {
+	counts, err := repositories.CountRecordsByPinStatus()
+	if err != nil {
+		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
+		log.Printf("Error counting records by pin status: %v", err)
+		return
+	}
+	replicas, err := repositories.CountRecordPinsByProvider()
+	if err != nil {
+		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
+		log.Printf("Error counting record replicas: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	writePinMetrics(w, counts, pins.GetStatus())
+	writeReplicaMetrics(w, replicas)
+}
+
+func writePinMetrics(w io.Writer, counts map[string]int64, status pins.MonitorStatus) {
+	fmt.Fprintln(w, "# HELP consentis_records_pin_status Records that are not deleted, by the pin status of their content.")
+	fmt.Fprintln(w, "# TYPE consentis_records_pin_status gauge")
+	for _, pinStatus := range []string{"unchecked", pins.StatusPinned, pins.StatusMissing} {
+		fmt.Fprintf(w, "consentis_records_pin_status{status=%q} %d\n", pinStatus, counts[pinStatus])
+	}
+
+	fmt.Fprintln(w, "# HELP consentis_pin_checks_total Pin health checks run by this process.")
+	fmt.Fprintln(w, "# TYPE consentis_pin_checks_total counter")
+	fmt.Fprintf(w, "consentis_pin_checks_total{result=\"success\"} %d\n", status.Checks)
+	fmt.Fprintf(w, "consentis_pin_checks_total{result=\"failure\"} %d\n", status.FailedChecks)
+
+	fmt.Fprintln(w, "# HELP consentis_pin_check_last_run_timestamp_seconds Start of the last pin health check.")
+	fmt.Fprintln(w, "# TYPE consentis_pin_check_last_run_timestamp_seconds gauge")
+	var lastRun int64
+	if !status.LastCheckAt.IsZero() {
+		lastRun = status.LastCheckAt.Unix()
+	}
+	fmt.Fprintf(w, "consentis_pin_check_last_run_timestamp_seconds %d\n", lastRun)
+
+	fmt.Fprintln(w, "# HELP consentis_pin_check_duration_seconds Duration of the last pin health check.")
+	fmt.Fprintln(w, "# TYPE consentis_pin_check_duration_seconds gauge")
+	fmt.Fprintf(w, "consentis_pin_check_duration_seconds %g\n", status.LastDuration.Seconds())
+
+	fmt.Fprintln(w, "# HELP consentis_pin_check_cids Distinct CIDs found by the last pin health check, by outcome.")
+	fmt.Fprintln(w, "# TYPE consentis_pin_check_cids gauge")
+	fmt.Fprintf(w, "consentis_pin_check_cids{status=%q} %d\n", pins.StatusPinned, status.Pinned)
+	fmt.Fprintf(w, "consentis_pin_check_cids{status=%q} %d\n", pins.StatusMissing, status.Missing)
+
+	fmt.Fprintln(w, "# HELP consentis_repins_total Missing content re-pinned by this process, by result.")
+	fmt.Fprintln(w, "# TYPE consentis_repins_total counter")
+	fmt.Fprintf(w, "consentis_repins_total{result=\"success\"} %d\n", status.Repins)
+	fmt.Fprintf(w, "consentis_repins_total{result=\"failure\"} %d\n", status.RepinFailures)
+}
+
+// writeReplicaMetrics writes the replica counts of each storage provider. It
+// writes nothing when replication is not configured.
+func writeReplicaMetrics(w io.Writer, counts map[string]map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "# HELP consentis_record_replicas Replicas of records that are not deleted, by storage provider and status.")
+	fmt.Fprintln(w, "# TYPE consentis_record_replicas gauge")
+	for _, provider := range slices.Sorted(maps.Keys(counts)) {
+		for _, replicaStatus := range []string{"pending", "pinned", "failed"} {
+			fmt.Fprintf(w, "consentis_record_replicas{provider=%q,status=%q} %d\n",
+				provider, replicaStatus, counts[provider][replicaStatus])
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"consentis-api/internal/pins"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePinMetrics(t *testing.T) {
+	var out strings.Builder
+	writePinMetrics(&out, map[string]int64{"pinned": 7, "missing": 1}, pins.MonitorStatus{
+		Checks:        3,
+		LastCheckAt:   time.Unix(1700000000, 0),
+		LastDuration:  1500 * time.Millisecond,
+		Pinned:        6,
+		Missing:       1,
+		Repins:        2,
+		RepinFailures: 4,
+	})
+
+	for _, line := range []string{
+		`consentis_records_pin_status{status="unchecked"} 0`,
+		`consentis_records_pin_status{status="pinned"} 7`,
+		`consentis_records_pin_status{status="missing"} 1`,
+		`consentis_pin_checks_total{result="success"} 3`,
+		`consentis_pin_check_last_run_timestamp_seconds 1700000000`,
+		`consentis_pin_check_duration_seconds 1.5`,
+		`consentis_repins_total{result="success"} 2`,
+		`consentis_repins_total{result="failure"} 4`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected metric line %q in:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestWriteReplicaMetrics(t *testing.T) {
+	var out strings.Builder
+	writeReplicaMetrics(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("Expected no replica metrics without replication, got:\n%s", out.String())
+	}
+
+	writeReplicaMetrics(&out, map[string]map[string]int64{
+		"pinata": {"pinned": 5},
+		"kubo":   {"pinned": 3, "failed": 2},
+	})
+	for _, line := range []string{
+		`consentis_record_replicas{provider="kubo",status="pending"} 0`,
+		`consentis_record_replicas{provider="kubo",status="failed"} 2`,
+		`consentis_record_replicas{provider="pinata",status="pinned"} 5`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected metric line %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -1,95 +1,342 @@
 package handlers
 
 import (
+	"consentis-api/internal/access"
+	"consentis-api/internal/auth"
 	"consentis-api/internal/dtos"
 	"consentis-api/internal/helpers"
 	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
 	"consentis-api/internal/repositories"
+	"consentis-api/internal/uploads"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
-// TODO: Implement soft delete for records
-// - Add DELETE /api/v1/records/{id} endpoint
-// - Add deleted_at column to records table
-// - Filter out deleted records in queries
-// - Optional: auto-revoke all consents on delete
 func StartRecordsHandler(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/v1/records", addRecord)
 	mux.HandleFunc("GET /api/v1/records/researcher/{address}", getRecordsByResearcherAddress)
 	mux.HandleFunc("GET /api/v1/records/patient/{address}", getRecordsByOwnerAddress)
+	mux.HandleFunc("GET /api/v1/records/patient/{address}/consents", getPatientConsentOverview)
+	mux.HandleFunc("GET /api/v1/records/{id}", getRecordByID)
+	mux.HandleFunc("GET /api/v1/record-access/{id}", getRecordAccess)
+	mux.HandleFunc("GET /api/v1/record-content/{id}", getRecordContent)
+	mux.HandleFunc("GET /api/v1/record-access-logs/{id}", getRecordAccessLogs)
+	mux.HandleFunc("DELETE /api/v1/records/{id}", deleteRecord)
 }
 
+// Metadata fields are small; the body limit leaves room for them and the
+// multipart framing on top of the file itself.
+const (
+	maxRecordFieldSize = 64 << 10
+	maxUploadOverhead  = 1 << 20
+
+	maxIdempotencyKeyLength = 255
+)
+
+var errFileBeforeFields = errors.New("file must be sent after the metadata fields")
+
+// addRecord queues the upload and answers 202 with the job, which the upload
+// workers pin and save in the background. Metadata fields are read and
+// validated first, then the file part is streamed to the staging directory,
+// so memory per upload stays constant and invalid requests fail before the
+// file is read. A retry of an earlier upload, matched by Idempotency-Key or
+// record_id, is answered from it without staging the file again.
 func addRecord(w http.ResponseWriter, r *http.Request) {
-	recordDto := dtos.RecordCreateRequest{
-		ID:                r.FormValue("record_id"),
-		PatientAddress:    r.FormValue("patient_address"),
-		Name:              r.FormValue("name"),
-		ACCJson:           json.RawMessage(r.FormValue("acc_json")),
-		DataToEncryptHash: r.FormValue("data_to_encrypt_hash"),
+	r.Body = http.MaxBytesReader(w, r.Body, ipfs.MaxFileSize+maxUploadOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
 	}
 
-	err := helpers.ValidateRecord(recordDto)
+	recordDto, file, err := readRecordForm(reader)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRecordFormError(w, err)
 		log.Printf("Bad Request: %v", err)
 		return
 	}
 
-	ctx := r.Context()
-	const maxUploadSize = 10 << 20 // 10 MB
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	err = helpers.ValidateRecord(recordDto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Printf("Bad Request: %v", err)
+		return
+	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+	if !authorizeWallet(w, r, recordDto.PatientAddress) {
 		return
 	}
 
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
+	if file == nil {
 		http.Error(w, "file is required", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	client, err := ipfs.GetClient()
-	if err != nil {
-		http.Error(w, "Failed to initialize IPFS client", http.StatusInternalServerError)
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if !validIdempotencyKey(idempotencyKey) {
+		http.Error(w, "Invalid Idempotency-Key header", http.StatusBadRequest)
 		return
 	}
 
-	res, err := client.StreamToPinata(ctx, file, fileHeader.Filename,
-		&ipfs.PinataMetadata{
-			Name: recordDto.Name,
-			Keyvalues: map[string]string{
-				"patient": recordDto.PatientAddress,
-			},
-		},
-		&ipfs.PinataOptions{CidVersion: 1},
-	)
+	if replayPriorUpload(w, recordDto, idempotencyKey) {
+		return
+	}
+
+	body := &clientBodyReader{r: file}
+	stagingPath, size, err := uploads.Stage(body)
 	if err != nil {
-		http.Error(w, "IPFS upload failed", http.StatusBadGateway)
+		switch {
+		case body.err != nil:
+			// The client's body failed, not the staging disk.
+			writeRecordFormError(w, body.err)
+		case errors.Is(err, ipfs.ErrFileTooLarge):
+			http.Error(w, "File exceeds the upload size limit", http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, "Failed to stage upload", http.StatusInternalServerError)
+		}
+		log.Printf("Error staging upload: %v", err)
 		return
 	}
 
-	record := helpers.ConvertDtoToRecordModel(recordDto)
-	record.IPFSCid = res.IpfsHash
-	log.Println("Record IPFS CID:", record.IPFSCid)
-	if err := repositories.CreateRecord(record, recordDto.PatientAddress); err != nil {
-		http.Error(w, "Failed to add record", http.StatusInternalServerError)
-		log.Printf("Error inserting record: %v", err)
+	job, err := repositories.CreateUploadJob(models.UploadJob{
+		RecordID:          recordDto.ID,
+		PatientAddress:    recordDto.PatientAddress,
+		Name:              recordDto.Name,
+		DataToEncryptHash: recordDto.DataToEncryptHash,
+		AccJson:           recordDto.ACCJson,
+		StagingPath:       stagingPath,
+		ContentSize:       size,
+		IdempotencyKey:    idempotencyKey,
+	})
+	if errors.Is(err, repositories.ErrUploadExists) {
+		// A concurrent request queued it first.
+		uploads.RemoveStaged(stagingPath)
+		if !replayPriorUpload(w, recordDto, idempotencyKey) {
+			http.Error(w, "Failed to queue upload", http.StatusInternalServerError)
+		}
+		return
+	}
+	if err != nil {
+		uploads.RemoveStaged(stagingPath)
+		http.Error(w, "Failed to queue upload", http.StatusInternalServerError)
+		log.Printf("Error queuing upload: %v", err)
 		return
 	}
+	uploads.Notify()
 
+	writeUploadJob(w, job)
+}
+
+func writeUploadJob(w http.ResponseWriter, job *dtos.UploadJobResponse) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message":   "Record added successfully",
-		"cid":       record.IPFSCid,
-		"record_id": record.ID,
-	})
+	w.Header().Set("Location", "/api/v1/uploads/"+job.Id)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// replayPriorUpload answers an upload matching an earlier one, by
+// Idempotency-Key or else by record id, without reading the file again: a
+// retry gets the earlier answer with Idempotent-Replayed set, and a request
+// differing from it gets 409. It returns false, without answering, when
+// there is no earlier upload.
+func replayPriorUpload(w http.ResponseWriter, recordDto dtos.RecordCreateRequest, idempotencyKey string) bool {
+	prior, err := repositories.FindPriorUpload(recordDto.PatientAddress, idempotencyKey, recordDto.ID)
+	if err != nil {
+		http.Error(w, "Failed to add record", http.StatusInternalServerError)
+		log.Printf("Error finding prior upload: %v", err)
+		return true
+	}
+	if prior == nil {
+		return false
+	}
+
+	if conflict := uploadConflict(prior, recordDto, idempotencyKey); conflict != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(conflict)
+		return true
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	if prior.JobID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(dtos.RecordCreatedResponse{
+			Message:  "Record added successfully",
+			Cid:      prior.IPFSCid,
+			RecordID: prior.RecordID,
+		})
+		return true
+	}
+
+	job, _, err := repositories.GetUploadJob(prior.JobID)
+	if err != nil || job == nil {
+		w.Header().Del("Idempotent-Replayed")
+		http.Error(w, "Failed to add record", http.StatusInternalServerError)
+		log.Printf("Error fetching upload job %s: %v", prior.JobID, err)
+		return true
+	}
+	writeUploadJob(w, job)
+	return true
+}
+
+// uploadConflict tells whether the request is a retry of the prior upload:
+// same record, same patient and same data_to_encrypt_hash. Otherwise it
+// returns the 409 body. A deleted record is never replayed, as its id cannot
+// be used again.
+func uploadConflict(prior *models.PriorUpload, recordDto dtos.RecordCreateRequest, idempotencyKey string) *dtos.UploadConflictResponse {
+	if prior.RecordDeleted && prior.RecordID == recordDto.ID {
+		return &dtos.UploadConflictResponse{
+			Error:    "record_deleted",
+			Message:  "A record with this id was deleted",
+			RecordID: recordDto.ID,
+		}
+	}
+
+	sameUpload := prior.RecordID == recordDto.ID &&
+		auth.SameAddress(prior.PatientAddress, recordDto.PatientAddress) &&
+		prior.DataToEncryptHash == recordDto.DataToEncryptHash
+	if sameUpload {
+		return nil
+	}
+
+	if idempotencyKey != "" && prior.IdempotencyKey == idempotencyKey {
+		// Keys are looked up among the caller's own uploads only.
+		return &dtos.UploadConflictResponse{
+			Error:    "idempotency_key_reused",
+			Message:  "Idempotency-Key was already used for another upload",
+			RecordID: prior.RecordID,
+			UploadID: prior.JobID,
+		}
+	}
+
+	conflict := &dtos.UploadConflictResponse{
+		Error:    "record_exists",
+		Message:  "A record with this id already exists with different content",
+		RecordID: recordDto.ID,
+	}
+	if auth.SameAddress(prior.PatientAddress, recordDto.PatientAddress) {
+		conflict.UploadID = prior.JobID
+	}
+	return conflict
+}
+
+// validIdempotencyKey accepts an absent key or up to 255 printable ASCII
+// characters.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// readRecordForm reads the metadata fields of a record upload up to the file
+// part, which is returned unread, or nil when the form has none. The file has
+// to come last: anything after it would only be reachable by buffering it.
+func readRecordForm(reader *multipart.Reader) (dtos.RecordCreateRequest, *multipart.Part, error) {
+	var recordDto dtos.RecordCreateRequest
+	for fields := 0; ; fields++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return recordDto, nil, nil
+		}
+		if err != nil {
+			return recordDto, nil, err
+		}
+
+		if part.FormName() == "file" {
+			if fields == 0 {
+				part.Close()
+				return recordDto, nil, errFileBeforeFields
+			}
+			return recordDto, part, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxRecordFieldSize+1))
+		part.Close()
+		if err != nil {
+			return recordDto, nil, err
+		}
+		if len(value) > maxRecordFieldSize {
+			return recordDto, nil, fmt.Errorf("field %s exceeds %d bytes", part.FormName(), maxRecordFieldSize)
+		}
+
+		switch part.FormName() {
+		case "record_id":
+			recordDto.ID = string(value)
+		case "patient_address":
+			recordDto.PatientAddress = string(value)
+		case "name":
+			recordDto.Name = string(value)
+		case "acc_json":
+			recordDto.ACCJson = json.RawMessage(value)
+		case "data_to_encrypt_hash":
+			recordDto.DataToEncryptHash = string(value)
+		}
+	}
+}
+
+// writeRecordFormError answers a malformed or oversized upload body.
+func writeRecordFormError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		http.Error(w, "Request body exceeds the upload size limit", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, errFileBeforeFields):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+	}
+}
+
+// clientBodyReader remembers the first error reading the client's upload, so
+// a truncated or oversized body is not reported as a provider failure.
+type clientBodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (c *clientBodyReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// reprDigest formats a hex SHA-256 as an RFC 9530 Repr-Digest header value.
+// The digest covers the whole file, so it is valid on range responses too.
+func reprDigest(sha256Hex *string) (string, bool) {
+	if sha256Hex == nil {
+		return "", false
+	}
+	sum, err := hex.DecodeString(*sha256Hex)
+	if err != nil || len(sum) != sha256.Size {
+		return "", false
+	}
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum) + ":", true
 }
 
 func getRecordsByResearcherAddress(w http.ResponseWriter, r *http.Request) {
@@ -104,14 +351,35 @@ func getRecordsByResearcherAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	records, err := repositories.GetAllRecords(address)
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if !isValidConsentStatusFilter(status) {
+		http.Error(w, "Invalid status filter, expected granted, revoked, pending or none", http.StatusBadRequest)
+		return
+	}
+
+	// Only records with a granted consent expose their CID and ACC, every
+	// other slice is served as discovery metadata.
+	var records any
+	var count int
+	var err error
+	if status == "granted" {
+		accessible, accessibleErr := repositories.GetAccessibleRecords(address)
+		records, count, err = accessible, len(accessible), accessibleErr
+	} else {
+		discoverable, discoverableErr := repositories.GetDiscoverableRecords(address, status)
+		records, count, err = discoverable, len(discoverable), discoverableErr
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve records", http.StatusInternalServerError)
 		log.Printf("Error retrieving records: %v", err)
 		return
 	}
 
-	if len(records) == 0 {
+	if count == 0 {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("[]"))
@@ -144,7 +412,12 @@ func getRecordsByOwnerAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	records, err := repositories.GetRecordsByOwnerAddress(address)
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
+	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
+	records, err := repositories.GetRecordsByOwnerAddress(address, includeDeleted)
 	if err != nil {
 		http.Error(w, "Failed to retrieve records", http.StatusInternalServerError)
 		log.Printf("Error retrieving records: %v", err)
@@ -171,3 +444,405 @@ func getRecordsByOwnerAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// getPatientConsentOverview shows a patient which researchers hold consents on
+// their records, grouped per record and per researcher.
+func getPatientConsentOverview(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if address == "" {
+		http.Error(w, "Address parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		http.Error(w, "Invalid Ethereum address format", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
+	consents, err := repositories.GetPatientConsents(address)
+	if err != nil {
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		log.Printf("Error retrieving patient consents: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(helpers.BuildConsentOverview(address, consents)); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+func getRecordByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	record, err := repositories.GetRecordByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record: %v", err)
+		return
+	}
+
+	if record == nil {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+
+	consents, err := repositories.GetConsentsByRecordID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		log.Printf("Error retrieving consents: %v", err)
+		return
+	}
+
+	scopeRecordToCaller(record, consents, wallet)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// getRecordAccess returns the CID and ACC of a record to the signed-in wallet
+// only when ConsentRegistry.checkAccess currently authorises it.
+func getRecordAccess(w http.ResponseWriter, r *http.Request) {
+	record, _, ok := loadAccessibleRecord(w, r)
+	if !ok {
+		return
+	}
+
+	response := dtos.RecordAccessResponse{
+		Id:                record.Id,
+		Name:              record.Name,
+		IPFSCid:           record.IPFSCid,
+		DataToEncryptHash: record.DataToEncryptHash,
+		AccJson:           record.AccJson,
+		PatientAddress:    record.PatientAddress,
+		ContentSHA256:     record.ContentSHA256,
+		ContentSize:       record.ContentSize,
+		CreatedAt:         record.CreatedAt,
+		VerifiedAt:        time.Now().UTC(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// getRecordContent streams the record's ciphertext to a wallet the contract
+// authorises, forwarding Range requests to the gateways, and logs the
+// download for the patient.
+func getRecordContent(w http.ResponseWriter, r *http.Request) {
+	record, wallet, ok := loadAccessibleRecord(w, r)
+	if !ok {
+		return
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	if !strings.HasPrefix(rangeHeader, "bytes=") {
+		rangeHeader = ""
+	}
+
+	content, err := openRecordContent(r.Context(), record.IPFSCid, rangeHeader)
+	if err != nil {
+		if errors.Is(err, ipfs.ErrNotFound) {
+			http.Error(w, "Record content not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve record content", http.StatusBadGateway)
+		log.Printf("Error fetching record content: %v", err)
+		return
+	}
+	defer content.Body.Close()
+
+	for _, header := range []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"} {
+		if value := content.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	contentType := content.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	if digest, ok := reprDigest(record.ContentSHA256); ok {
+		w.Header().Set("Repr-Digest", digest)
+	}
+
+	if content.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		w.WriteHeader(content.StatusCode)
+		return
+	}
+
+	// Downloads can outlast the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Println("Error clearing write deadline:", err)
+	}
+
+	w.WriteHeader(content.StatusCode)
+	hash := sha256.New()
+	served, err := io.Copy(io.MultiWriter(w, hash), content.Body)
+	if err != nil {
+		log.Printf("Error streaming record %s content: %v", record.Id, err)
+	}
+
+	// A full download can be checked against the upload. The bytes are
+	// already sent, the client checks them against Repr-Digest; this only
+	// surfaces a gateway serving the wrong content.
+	if err == nil && content.StatusCode == http.StatusOK && record.ContentSHA256 != nil &&
+		hex.EncodeToString(hash.Sum(nil)) != *record.ContentSHA256 {
+		log.Printf("Record %s content from %s does not match its SHA-256 (%d bytes served)", record.Id, content.Gateway, served)
+	}
+
+	entry := models.RecordAccessLog{
+		RecordID:        record.Id,
+		AccessorAddress: wallet,
+		ByteRange:       rangeHeader,
+		StatusCode:      content.StatusCode,
+		BytesServed:     served,
+		Gateway:         content.Gateway,
+	}
+	if err := repositories.SaveRecordAccessLog(entry); err != nil {
+		log.Printf("Error logging access to record %s: %v", record.Id, err)
+	}
+}
+
+// openRecordContent reads a whole record from the storage backend that pins
+// it, falling back to the IPFS gateways when the backend cannot serve it.
+// Backends have no range reads, so Range requests go to the gateways, as do
+// reads from backends that are served by them anyway.
+func openRecordContent(ctx context.Context, cid string, rangeHeader string) (*ipfs.GatewayResponse, error) {
+	if rangeHeader == "" {
+		storage, err := ipfs.GetStorage()
+		if err == nil && !ipfs.ServedByGateway(storage) {
+			var body io.ReadCloser
+			body, err = storage.Get(ctx, cid)
+			if err == nil {
+				return &ipfs.GatewayResponse{
+					Response: &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body},
+					Gateway:  "storage",
+				}, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Error reading %s from storage, trying the gateways: %v", cid, err)
+		}
+	}
+	return ipfs.GetGateway().Fetch(ctx, cid, rangeHeader)
+}
+
+// getRecordAccessLogs lists the downloads of a record to its owner.
+func getRecordAccessLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return
+	}
+
+	owner, found, err := repositories.GetRecordOwnerAddress(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record owner: %v", err)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeWallet(w, r, owner) {
+		return
+	}
+
+	logs, err := repositories.GetRecordAccessLogs(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve access logs", http.StatusInternalServerError)
+		log.Printf("Error retrieving access logs: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(logs); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// loadAccessibleRecord loads the record in the path and checks on-chain that
+// the signed-in wallet may read it, writing the error response otherwise. The
+// database consent state is not trusted for this, it may lag the chain.
+func loadAccessibleRecord(w http.ResponseWriter, r *http.Request) (*dtos.RecordDetailResponse, string, bool) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	record, err := repositories.GetRecordByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record: %v", err)
+		return nil, "", false
+	}
+
+	if record == nil {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return nil, "", false
+	}
+
+	allowed, err := access.GetVerifier().CheckAccess(r.Context(), record.PatientAddress, wallet, id)
+	if err != nil {
+		http.Error(w, "Unable to verify access on-chain", http.StatusServiceUnavailable)
+		log.Printf("Error verifying access: %v", err)
+		return nil, "", false
+	}
+
+	if !allowed {
+		http.Error(w, "Access not granted on-chain", http.StatusForbidden)
+		return nil, "", false
+	}
+
+	return record, wallet, true
+}
+
+// deleteRecord soft deletes a record owned by the caller. With
+// ?revoke_consents=true the record's consents are marked revoked and the
+// unsigned revokeConsent calls for researchers that held a granted consent are
+// returned so the patient can finish the revocation on-chain.
+func deleteRecord(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid record id", http.StatusBadRequest)
+		return
+	}
+
+	record, err := repositories.GetRecordByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+		log.Printf("Error retrieving record: %v", err)
+		return
+	}
+
+	if record == nil {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeWallet(w, r, record.PatientAddress) {
+		return
+	}
+
+	revokeConsents := r.URL.Query().Get("revoke_consents") == "true"
+	activeResearchers, err := repositories.SoftDeleteRecord(id, revokeConsents)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete record", http.StatusInternalServerError)
+		log.Printf("Error deleting record: %v", err)
+		return
+	}
+
+	response := dtos.RecordDeleteResponse{
+		Message:        "Record deleted successfully",
+		RecordID:       id,
+		PendingRevokes: []dtos.UnsignedContractCall{},
+	}
+
+	contractAddress := os.Getenv("CONTRACT_ADDRESS")
+	for _, researcher := range activeResearchers {
+		data, err := helpers.BuildRevokeConsentCalldata(researcher, id)
+		if err != nil {
+			log.Printf("Error encoding revokeConsent calldata: %v", err)
+			continue
+		}
+		response.PendingRevokes = append(response.PendingRevokes, dtos.UnsignedContractCall{
+			ResearcherAddress: researcher,
+			To:                contractAddress,
+			Data:              data,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error writing response:", err)
+		return
+	}
+}
+
+// scopeRecordToCaller gives the owner every consent, while any other wallet
+// only sees its own consent row and only receives the CID, ACC and owner once
+// that consent is granted.
+func scopeRecordToCaller(record *dtos.RecordDetailResponse, consents []dtos.RecordConsentResponse, wallet string) {
+	if auth.SameAddress(record.PatientAddress, wallet) {
+		record.Consents = consents
+		return
+	}
+
+	record.Consents = []dtos.RecordConsentResponse{}
+	granted := false
+	for _, consent := range consents {
+		if auth.SameAddress(consent.ResearcherAddress, wallet) {
+			record.Consents = append(record.Consents, consent)
+			granted = consent.Status == "granted"
+		}
+	}
+
+	if !granted {
+		record.IPFSCid = ""
+		record.DataToEncryptHash = ""
+		record.AccJson = nil
+		record.PatientAddress = ""
+		record.ContentSHA256 = nil
+		record.ContentSize = nil
+	}
+}
+
+func isValidConsentStatusFilter(status string) bool {
+	switch status {
+	case "", "granted", "revoked", "pending", "none":
+		return true
+	}
+	return false
+}
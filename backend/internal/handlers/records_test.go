@@ -2,6 +2,11 @@ package handlers
 
 import (
 	"bytes"
+	"consentis-api/internal/auth"
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/models"
+	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -117,6 +122,7 @@ func TestAddRecord_MissingFile(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(auth.WithWallet(req.Context(), "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"))
 	w := httptest.NewRecorder()
 
 	addRecord(w, req)
@@ -173,6 +179,7 @@ func TestAddRecord_InvalidRecordID(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(auth.WithWallet(req.Context(), "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"))
 	w := httptest.NewRecorder()
 
 	addRecord(w, req)
@@ -182,3 +189,488 @@ func TestAddRecord_InvalidRecordID(t *testing.T) {
 		t.Errorf("Expected status 400 or 500, got %d", w.Code)
 	}
 }
+
+func TestAddRecord_Unauthenticated(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	writer.WriteField("record_id", "550e8400-e29b-41d4-a716-446655440000")
+	writer.WriteField("patient_address", "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2")
+	writer.WriteField("name", "Test Record")
+	writer.WriteField("acc_json", "{}")
+	writer.WriteField("data_to_encrypt_hash", "0xabc123")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	addRecord(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestAddRecord_WalletMismatch(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	writer.WriteField("record_id", "550e8400-e29b-41d4-a716-446655440000")
+	writer.WriteField("patient_address", "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2")
+	writer.WriteField("name", "Test Record")
+	writer.WriteField("acc_json", "{}")
+	writer.WriteField("data_to_encrypt_hash", "0xabc123")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(auth.WithWallet(req.Context(), "0x1234567890123456789012345678901234567890"))
+	w := httptest.NewRecorder()
+
+	addRecord(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestGetRecordsByOwnerAddress_WalletMismatch(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/patient/"+address, nil)
+	req.SetPathValue("address", address)
+	req = req.WithContext(auth.WithWallet(req.Context(), "0x1234567890123456789012345678901234567890"))
+	w := httptest.NewRecorder()
+
+	getRecordsByOwnerAddress(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestGetRecordsByResearcherAddress_InvalidStatusFilter(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/researcher/"+address+"?status=everything", nil)
+	req.SetPathValue("address", address)
+	req = req.WithContext(auth.WithWallet(req.Context(), address))
+	w := httptest.NewRecorder()
+
+	getRecordsByResearcherAddress(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetRecordByID_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/records/not-a-uuid", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	getRecordByID(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetRecordAccess_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/record-access/not-a-uuid", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	getRecordAccess(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetRecordAccess_Unauthenticated(t *testing.T) {
+	id := "550e8400-e29b-41d4-a716-446655440000"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/record-access/"+id, nil)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+
+	getRecordAccess(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestGetRecordContent_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/record-content/not-a-uuid", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	getRecordContent(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetRecordAccessLogs_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/record-access-logs/not-a-uuid", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	getRecordAccessLogs(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetPatientConsentOverview(t *testing.T) {
+	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+
+	tests := []struct {
+		name         string
+		address      string
+		wallet       string
+		expectedCode int
+	}{
+		{"Missing address", "", address, http.StatusBadRequest},
+		{"Invalid address", "0x123", address, http.StatusBadRequest},
+		{"Unauthenticated", address, "", http.StatusUnauthorized},
+		{"Wallet mismatch", address, "0x0000000000000000000000000000000000000001", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/records/patient/"+tt.address+"/consents", nil)
+			req.SetPathValue("address", tt.address)
+			if tt.wallet != "" {
+				req = req.WithContext(auth.WithWallet(req.Context(), tt.wallet))
+			}
+			w := httptest.NewRecorder()
+
+			getPatientConsentOverview(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestScopeRecordToCaller(t *testing.T) {
+	owner := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	granted := "0x1111111111111111111111111111111111111111"
+	revoked := "0x2222222222222222222222222222222222222222"
+
+	checksum := strings.Repeat("ab", 32)
+	newRecord := func() *dtos.RecordDetailResponse {
+		return &dtos.RecordDetailResponse{
+			Id:             "550e8400-e29b-41d4-a716-446655440000",
+			IPFSCid:        "bafy",
+			AccJson:        []byte(`{}`),
+			PatientAddress: owner,
+			ContentSHA256:  &checksum,
+		}
+	}
+	consents := []dtos.RecordConsentResponse{
+		{ResearcherAddress: granted, Status: "granted"},
+		{ResearcherAddress: revoked, Status: "revoked"},
+	}
+
+	tests := []struct {
+		name         string
+		wallet       string
+		wantConsents int
+		wantCid      bool
+	}{
+		{"Owner sees every consent", strings.ToLower(owner), 2, true},
+		{"Granted researcher sees own row", granted, 1, true},
+		{"Revoked researcher loses material", revoked, 1, false},
+		{"Stranger sees nothing", "0x3333333333333333333333333333333333333333", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := newRecord()
+			scopeRecordToCaller(record, consents, tt.wallet)
+
+			if len(record.Consents) != tt.wantConsents {
+				t.Errorf("Expected %d consents, got %d", tt.wantConsents, len(record.Consents))
+			}
+			if (record.IPFSCid != "") != tt.wantCid {
+				t.Errorf("Expected CID visible = %v, got %q", tt.wantCid, record.IPFSCid)
+			}
+			if (record.ContentSHA256 != nil) != tt.wantCid {
+				t.Errorf("Expected checksum visible = %v", tt.wantCid)
+			}
+		})
+	}
+}
+
+func TestDeleteRecord_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/records/not-a-uuid", nil)
+	req.SetPathValue("id", "not-a-uuid")
+	w := httptest.NewRecorder()
+
+	deleteRecord(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestReprDigest(t *testing.T) {
+	// SHA-256 of the empty string
+	empty := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	invalid := "not-hex"
+
+	tests := []struct {
+		name   string
+		value  *string
+		want   string
+		wantOk bool
+	}{
+		{"Checksum", &empty, "sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:", true},
+		{"Not recorded", nil, "", false},
+		{"Invalid", &invalid, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reprDigest(tt.value)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("reprDigest() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestReadRecordForm(t *testing.T) {
+	writeFields := func(writer *multipart.Writer) {
+		writer.WriteField("record_id", "550e8400-e29b-41d4-a716-446655440000")
+		writer.WriteField("patient_address", "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2")
+		writer.WriteField("name", "Test Record")
+		writer.WriteField("acc_json", "{}")
+		writer.WriteField("data_to_encrypt_hash", "0xabc123")
+	}
+	writeFile := func(writer *multipart.Writer) {
+		part, _ := writer.CreateFormFile("file", "encrypted-record.bin")
+		part.Write([]byte("ciphertext"))
+	}
+
+	tests := []struct {
+		name     string
+		build    func(writer *multipart.Writer)
+		wantFile string
+		wantErr  error
+		wantOk   bool
+	}{
+		{
+			name: "Fields then file",
+			build: func(writer *multipart.Writer) {
+				writeFields(writer)
+				writeFile(writer)
+			},
+			wantFile: "ciphertext",
+			wantOk:   true,
+		},
+		{
+			name:   "No file",
+			build:  writeFields,
+			wantOk: true,
+		},
+		{
+			name: "File first",
+			build: func(writer *multipart.Writer) {
+				writeFile(writer)
+				writeFields(writer)
+			},
+			wantErr: errFileBeforeFields,
+		},
+		{
+			name: "Oversized field",
+			build: func(writer *multipart.Writer) {
+				writer.WriteField("acc_json", strings.Repeat("a", maxRecordFieldSize+1))
+				writeFile(writer)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			tt.build(writer)
+			writer.Close()
+
+			recordDto, file, err := readRecordForm(multipart.NewReader(body, writer.Boundary()))
+			if (err == nil) != tt.wantOk {
+				t.Fatalf("readRecordForm() error = %v, wantOk %v", err, tt.wantOk)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("readRecordForm() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.wantOk {
+				return
+			}
+
+			if recordDto.Name != "Test Record" || string(recordDto.ACCJson) != "{}" {
+				t.Errorf("Unexpected fields %+v", recordDto)
+			}
+			if tt.wantFile == "" {
+				if file != nil {
+					t.Error("Expected no file part")
+				}
+				return
+			}
+			content, _ := io.ReadAll(file)
+			if string(content) != tt.wantFile {
+				t.Errorf("Expected file %q, got %q", tt.wantFile, content)
+			}
+		})
+	}
+}
+
+func TestAddRecord_BodyTooLarge(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", strings.Repeat("a", maxRecordFieldSize))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	// Shrink the limit through the request itself: a body over the limit
+	// fails while the fields are read, before anything reaches storage.
+	req.Body = http.MaxBytesReader(w, req.Body, 1024)
+	addRecord(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", w.Code)
+	}
+}
+
+func TestAddRecord_InvalidIdempotencyKey(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("record_id", "550e8400-e29b-41d4-a716-446655440000")
+	writer.WriteField("patient_address", "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2")
+	writer.WriteField("name", "Test Record")
+	writer.WriteField("acc_json", "{}")
+	writer.WriteField("data_to_encrypt_hash", "0xabc123")
+	part, _ := writer.CreateFormFile("file", "record.bin")
+	part.Write([]byte("ciphertext"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/records", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Idempotency-Key", "not a valid key")
+	req = req.WithContext(auth.WithWallet(req.Context(), "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"))
+	w := httptest.NewRecorder()
+
+	addRecord(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestValidIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"", true},
+		{"4f0c2a1e-upload-1", true},
+		{strings.Repeat("k", maxIdempotencyKeyLength), true},
+		{strings.Repeat("k", maxIdempotencyKeyLength+1), false},
+		{"with space", false},
+		{"caf\u00e9", false},
+	}
+
+	for _, tt := range tests {
+		if got := validIdempotencyKey(tt.key); got != tt.want {
+			t.Errorf("validIdempotencyKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestUploadConflict(t *testing.T) {
+	const (
+		recordID = "550e8400-e29b-41d4-a716-446655440000"
+		patient  = "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	)
+	request := dtos.RecordCreateRequest{ID: recordID, PatientAddress: patient, DataToEncryptHash: "0xabc123"}
+
+	tests := []struct {
+		name      string
+		prior     models.PriorUpload
+		key       string
+		wantError string
+		wantJob   string
+	}{
+		{
+			name:  "Retry of a queued upload",
+			prior: models.PriorUpload{JobID: "job-1", RecordID: recordID, PatientAddress: strings.ToLower(patient), DataToEncryptHash: "0xabc123"},
+		},
+		{
+			name:  "Retry of a record saved without a job",
+			prior: models.PriorUpload{RecordID: recordID, PatientAddress: patient, DataToEncryptHash: "0xabc123", IPFSCid: "bafy"},
+		},
+		{
+			name:      "Retry of a deleted record",
+			prior:     models.PriorUpload{RecordID: recordID, PatientAddress: patient, DataToEncryptHash: "0xabc123", IPFSCid: "bafy", RecordDeleted: true},
+			wantError: "record_deleted",
+		},
+		{
+			name:      "Retry of a job whose record was deleted",
+			prior:     models.PriorUpload{JobID: "job-1", RecordID: recordID, PatientAddress: patient, DataToEncryptHash: "0xabc123", RecordDeleted: true},
+			key:       "key-1",
+			wantError: "record_deleted",
+		},
+		{
+			name:      "Same record, different content",
+			prior:     models.PriorUpload{JobID: "job-1", RecordID: recordID, PatientAddress: patient, DataToEncryptHash: "0xother"},
+			wantError: "record_exists",
+			wantJob:   "job-1",
+		},
+		{
+			name:      "Record of another patient",
+			prior:     models.PriorUpload{JobID: "job-1", RecordID: recordID, PatientAddress: "0x1234567890123456789012345678901234567890", DataToEncryptHash: "0xabc123"},
+			wantError: "record_exists",
+		},
+		{
+			name:      "Key used for another record",
+			prior:     models.PriorUpload{JobID: "job-2", RecordID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", PatientAddress: patient, DataToEncryptHash: "0xabc123", IdempotencyKey: "key-1"},
+			key:       "key-1",
+			wantError: "idempotency_key_reused",
+			wantJob:   "job-2",
+		},
+		{
+			name:      "Key reused with different content",
+			prior:     models.PriorUpload{JobID: "job-1", RecordID: recordID, PatientAddress: patient, DataToEncryptHash: "0xother", IdempotencyKey: "key-1"},
+			key:       "key-1",
+			wantError: "idempotency_key_reused",
+			wantJob:   "job-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := uploadConflict(&tt.prior, request, tt.key)
+			if tt.wantError == "" {
+				if conflict != nil {
+					t.Fatalf("Expected a retry, got conflict %+v", conflict)
+				}
+				return
+			}
+			if conflict == nil {
+				t.Fatal("Expected a conflict, got a retry")
+			}
+			if conflict.Error != tt.wantError || conflict.UploadID != tt.wantJob {
+				t.Errorf("Unexpected conflict %+v", conflict)
+			}
+		})
+	}
+}
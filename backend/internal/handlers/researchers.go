@@ -69,6 +69,10 @@ func saveResearcher(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeWallet(w, r, researcher.WalletAddress) {
+		return
+	}
+
 	researcherID, err := repositories.SaveResearcher(researcher)
 	if err != nil {
 		http.Error(w, "Failed to save researcher", http.StatusInternalServerError)
@@ -102,6 +106,10 @@ func updateResearcher(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeWallet(w, r, address) {
+		return
+	}
+
 	var researcher dtos.ResearcherUpdateDto
 	if err := json.NewDecoder(r.Body).Decode(&researcher); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
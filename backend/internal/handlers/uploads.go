@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"consentis-api/internal/auth"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/repositories"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func StartUploadsHandler(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/uploads/{jobId}", getUploadJob)
+}
+
+// getUploadJob reports the progress of a queued record upload to the patient
+// who sent it: its status, the CID once pinned, and why the last attempt
+// failed.
+func getUploadJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("jobId")
+	if !helpers.IsValidUUID(id) {
+		http.Error(w, "Invalid upload job id", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := auth.WalletFromContext(r.Context()); !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	job, patientAddress, err := repositories.GetUploadJob(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve upload job", http.StatusInternalServerError)
+		log.Printf("Error retrieving upload job: %v", err)
+		return
+	}
+
+	if job == nil {
+		http.Error(w, "Upload job not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeWallet(w, r, patientAddress) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUploadJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		jobID      string
+		wantStatus int
+	}{
+		{"Invalid id", "not-a-uuid", http.StatusBadRequest},
+		{"Unauthenticated", "550e8400-e29b-41d4-a716-446655440000", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/uploads/"+tt.jobID, nil)
+			req.SetPathValue("jobId", tt.jobID)
+			w := httptest.NewRecorder()
+
+			getUploadJob(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
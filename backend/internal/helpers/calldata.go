@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	consentRegistry "consentis-api/contracts"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// BuildRevokeConsentCalldata ABI-encodes ConsentRegistry.revokeConsent so the
+// patient's wallet can sign and send it.
+func BuildRevokeConsentCalldata(researcherAddress string, recordID string) (string, error) {
+	parsedABI, err := consentRegistry.ConsentRegistryMetaData.GetAbi()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := parsedABI.Pack("revokeConsent", common.HexToAddress(researcherAddress), recordID)
+	if err != nil {
+		return "", err
+	}
+
+	return hexutil.Encode(data), nil
+}
+
+// BuildGrantConsentCalldata ABI-encodes ConsentRegistry.grantConsent so the
+// patient's wallet can sign and send it.
+func BuildGrantConsentCalldata(researcherAddress string, recordID string) (string, error) {
+	parsedABI, err := consentRegistry.ConsentRegistryMetaData.GetAbi()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := parsedABI.Pack("grantConsent", common.HexToAddress(researcherAddress), recordID)
+	if err != nil {
+		return "", err
+	}
+
+	return hexutil.Encode(data), nil
+}
+
+// RecordIDHash returns the topic RecordRegistered is indexed under for a
+// record id. Indexed strings are logged as their keccak256 hash.
+func RecordIDHash(recordID string) string {
+	return crypto.Keccak256Hash([]byte(recordID)).Hex()
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	consentRegistry "consentis-api/contracts"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestBuildRevokeConsentCalldata(t *testing.T) {
+	data, err := BuildRevokeConsentCalldata("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2", "550e8400-e29b-41d4-a716-446655440000")
+	if err != nil {
+		t.Fatalf("BuildRevokeConsentCalldata() unexpected error: %v", err)
+	}
+
+	// keccak256("revokeConsent(address,string)")[:4]
+	if !strings.HasPrefix(data, "0xbd41ad8b") {
+		t.Errorf("Expected revokeConsent selector, got %s", data[:10])
+	}
+	if !strings.Contains(strings.ToLower(data), "742d35cc6634c0532925a3b844bc9e7595f0beb2") {
+		t.Error("Expected researcher address in calldata")
+	}
+}
+
+func TestBuildGrantConsentCalldata(t *testing.T) {
+	data, err := BuildGrantConsentCalldata("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2", "550e8400-e29b-41d4-a716-446655440000")
+	if err != nil {
+		t.Fatalf("BuildGrantConsentCalldata() unexpected error: %v", err)
+	}
+
+	parsedABI, err := consentRegistry.ConsentRegistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("GetAbi() unexpected error: %v", err)
+	}
+	selector := hexutil.Encode(parsedABI.Methods["grantConsent"].ID)
+	if !strings.HasPrefix(data, selector) {
+		t.Errorf("Expected grantConsent selector %s, got %s", selector, data[:10])
+	}
+}
+
+func TestRecordIDHash(t *testing.T) {
+	if got := RecordIDHash(""); got != "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470" {
+		t.Errorf("Expected keccak256 of empty string, got %s", got)
+	}
+
+	// Must match how the ABI encodes an indexed string topic
+	id := "550e8400-e29b-41d4-a716-446655440000"
+	topics, err := abi.MakeTopics([]any{id})
+	if err != nil {
+		t.Fatalf("MakeTopics() unexpected error: %v", err)
+	}
+	if got := RecordIDHash(id); got != topics[0][0].Hex() {
+		t.Errorf("Expected %s, got %s", topics[0][0].Hex(), got)
+	}
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/models"
+	"slices"
+	"strings"
+)
+
+// BuildConsentOverview groups a patient's consents per record, in the order
+// the records are given, and per researcher, most recently changed first.
+func BuildConsentOverview(patientAddress string, consents []models.PatientConsent) dtos.PatientConsentOverviewResponse {
+	overview := dtos.PatientConsentOverviewResponse{
+		PatientAddress: patientAddress,
+		Records:        []dtos.RecordConsentSummary{},
+		Researchers:    []dtos.ResearcherConsentSummary{},
+	}
+
+	recordIndex := make(map[string]int)
+	researcherIndex := make(map[string]int)
+
+	for _, consent := range consents {
+		i, ok := recordIndex[consent.RecordID]
+		if !ok {
+			i = len(overview.Records)
+			recordIndex[consent.RecordID] = i
+			overview.Records = append(overview.Records, dtos.RecordConsentSummary{
+				RecordID:    consent.RecordID,
+				RecordName:  consent.RecordName,
+				Researchers: []dtos.ResearcherConsent{},
+			})
+		}
+
+		if consent.ResearcherAddress == "" {
+			continue
+		}
+
+		record := &overview.Records[i]
+		record.Researchers = append(record.Researchers, dtos.ResearcherConsent{
+			ResearcherAddress: consent.ResearcherAddress,
+			FullName:          consent.FullName,
+			Institution:       consent.Institution,
+			Status:            consent.Status,
+			UpdatedAt:         consent.UpdatedAt,
+		})
+		countConsent(&record.Counts, consent.Status)
+		if record.LastChangedAt == nil || consent.UpdatedAt.After(*record.LastChangedAt) {
+			updatedAt := consent.UpdatedAt
+			record.LastChangedAt = &updatedAt
+		}
+
+		key := strings.ToLower(consent.ResearcherAddress)
+		j, ok := researcherIndex[key]
+		if !ok {
+			j = len(overview.Researchers)
+			researcherIndex[key] = j
+			overview.Researchers = append(overview.Researchers, dtos.ResearcherConsentSummary{
+				ResearcherAddress: consent.ResearcherAddress,
+				FullName:          consent.FullName,
+				Institution:       consent.Institution,
+				Records:           []dtos.RecordConsent{},
+			})
+		}
+
+		researcher := &overview.Researchers[j]
+		researcher.Records = append(researcher.Records, dtos.RecordConsent{
+			RecordID:   consent.RecordID,
+			RecordName: consent.RecordName,
+			Status:     consent.Status,
+			UpdatedAt:  consent.UpdatedAt,
+		})
+		countConsent(&researcher.Counts, consent.Status)
+		if consent.UpdatedAt.After(researcher.LastChangedAt) {
+			researcher.LastChangedAt = consent.UpdatedAt
+		}
+
+		countConsent(&overview.Totals, consent.Status)
+	}
+
+	slices.SortStableFunc(overview.Researchers, func(a, b dtos.ResearcherConsentSummary) int {
+		return b.LastChangedAt.Compare(a.LastChangedAt)
+	})
+
+	return overview
+}
+
+func countConsent(counts *dtos.ConsentCounts, status string) {
+	switch status {
+	case "granted":
+		counts.Granted++
+	case "revoked":
+		counts.Revoked++
+	case "pending":
+		counts.Pending++
+	}
+}
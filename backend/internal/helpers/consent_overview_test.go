@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"consentis-api/internal/models"
+	"testing"
+	"time"
+)
+
+func TestBuildConsentOverview(t *testing.T) {
+	patient := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2"
+	alice := "0x1111111111111111111111111111111111111111"
+	bob := "0x2222222222222222222222222222222222222222"
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+
+	consents := []models.PatientConsent{
+		{RecordID: "rec-1", RecordName: "Blood test", ResearcherAddress: alice, FullName: "Alice", Institution: "Uni", Status: "granted", UpdatedAt: t1},
+		{RecordID: "rec-1", RecordName: "Blood test", ResearcherAddress: bob, Status: "revoked", UpdatedAt: t3},
+		{RecordID: "rec-2", RecordName: "MRI", ResearcherAddress: alice, FullName: "Alice", Institution: "Uni", Status: "pending", UpdatedAt: t2},
+		{RecordID: "rec-3", RecordName: "No consents"},
+	}
+
+	overview := BuildConsentOverview(patient, consents)
+
+	if overview.PatientAddress != patient {
+		t.Errorf("Expected patient %s, got %s", patient, overview.PatientAddress)
+	}
+	if overview.Totals.Granted != 1 || overview.Totals.Revoked != 1 || overview.Totals.Pending != 1 {
+		t.Errorf("Unexpected totals: %+v", overview.Totals)
+	}
+
+	if len(overview.Records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(overview.Records))
+	}
+	first := overview.Records[0]
+	if first.RecordID != "rec-1" || len(first.Researchers) != 2 {
+		t.Errorf("Expected rec-1 with 2 researchers, got %s with %d", first.RecordID, len(first.Researchers))
+	}
+	if first.LastChangedAt == nil || !first.LastChangedAt.Equal(t3) {
+		t.Errorf("Expected rec-1 last change %v, got %v", t3, first.LastChangedAt)
+	}
+	empty := overview.Records[2]
+	if len(empty.Researchers) != 0 || empty.LastChangedAt != nil {
+		t.Errorf("Expected rec-3 without consents, got %+v", empty)
+	}
+
+	if len(overview.Researchers) != 2 {
+		t.Fatalf("Expected 2 researchers, got %d", len(overview.Researchers))
+	}
+	if overview.Researchers[0].ResearcherAddress != bob {
+		t.Errorf("Expected most recently changed researcher first, got %s", overview.Researchers[0].ResearcherAddress)
+	}
+	aliceSummary := overview.Researchers[1]
+	if aliceSummary.FullName != "Alice" || aliceSummary.Institution != "Uni" {
+		t.Errorf("Expected Alice's profile, got %+v", aliceSummary)
+	}
+	if len(aliceSummary.Records) != 2 || aliceSummary.Counts.Granted != 1 || aliceSummary.Counts.Pending != 1 {
+		t.Errorf("Unexpected summary for Alice: %+v", aliceSummary)
+	}
+	if !aliceSummary.LastChangedAt.Equal(t2) {
+		t.Errorf("Expected Alice's last change %v, got %v", t2, aliceSummary.LastChangedAt)
+	}
+}
+
+func TestBuildConsentOverview_Empty(t *testing.T) {
+	overview := BuildConsentOverview("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2", nil)
+
+	if overview.Records == nil || overview.Researchers == nil {
+		t.Error("Expected empty lists rather than nil")
+	}
+}
@@ -8,6 +8,7 @@ import (
 func ConvertDtoToRecordModel(recordDto dtos.RecordCreateRequest) models.Record {
 	return models.Record{
 		ID:                recordDto.ID,
+		IDHash:            RecordIDHash(recordDto.ID),
 		Name:              recordDto.Name,
 		DataToEncryptHash: recordDto.DataToEncryptHash,
 		AccJson:           recordDto.ACCJson,
@@ -51,6 +51,9 @@ func TestConvertDtoToRecordModel(t *testing.T) {
 			if result.ID != tt.expected.ID {
 				t.Errorf("ID mismatch: got %v, want %v", result.ID, tt.expected.ID)
 			}
+			if result.IDHash != RecordIDHash(tt.input.ID) {
+				t.Errorf("IDHash mismatch: got %v, want %v", result.IDHash, RecordIDHash(tt.input.ID))
+			}
 			if result.Name != tt.expected.Name {
 				t.Errorf("Name mismatch: got %v, want %v", result.Name, tt.expected.Name)
 			}
@@ -3,9 +3,22 @@ package helpers
 import (
 	"consentis-api/internal/dtos"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 )
 
+const (
+	MaxAccessRequestPurposeLength = 1000
+	MaxAccessRequestDurationDays  = 365
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func IsValidUUID(id string) bool {
+	return uuidPattern.MatchString(id)
+}
+
 func ValidateRecord(record dtos.RecordCreateRequest) error {
 	if strings.TrimSpace(record.ID) == "" {
 		return errors.New("record_id is required and cannot be empty")
@@ -69,3 +82,27 @@ func ValidateResearcherUpdate(researcher dtos.ResearcherUpdateDto) error {
 
 	return nil
 }
+
+func ValidateAccessRequest(request dtos.AccessRequestCreateDto) error {
+	if !IsValidUUID(request.RecordID) {
+		return errors.New("record_id must be a valid UUID")
+	}
+
+	if len(request.ResearcherAddress) != 42 || !strings.HasPrefix(request.ResearcherAddress, "0x") {
+		return errors.New("Invalid Ethereum address format for researcher_address")
+	}
+
+	if strings.TrimSpace(request.Purpose) == "" {
+		return errors.New("Purpose is required and cannot be empty")
+	}
+
+	if len(request.Purpose) > MaxAccessRequestPurposeLength {
+		return fmt.Errorf("Purpose cannot exceed %d characters", MaxAccessRequestPurposeLength)
+	}
+
+	if request.DurationDays < 1 || request.DurationDays > MaxAccessRequestDurationDays {
+		return fmt.Errorf("duration_days must be between 1 and %d", MaxAccessRequestDurationDays)
+	}
+
+	return nil
+}
@@ -174,3 +174,63 @@ func TestValidateResearcherUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestIsValidUUID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"550e8400-e29b-41d4-a716-446655440000", true},
+		{"550E8400-E29B-41D4-A716-446655440000", true},
+		{"not-a-uuid", false},
+		{"550e8400e29b41d4a716446655440000", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := IsValidUUID(tt.id); got != tt.want {
+				t.Errorf("IsValidUUID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAccessRequest(t *testing.T) {
+	valid := dtos.AccessRequestCreateDto{
+		RecordID:          "550e8400-e29b-41d4-a716-446655440000",
+		ResearcherAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb2",
+		Purpose:           "Cardiology study",
+		DurationDays:      30,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *dtos.AccessRequestCreateDto)
+		wantErr bool
+		errMsg  string
+	}{
+		{"Valid request", func(r *dtos.AccessRequestCreateDto) {}, false, ""},
+		{"Invalid record id", func(r *dtos.AccessRequestCreateDto) { r.RecordID = "rec-1" }, true, "record_id"},
+		{"Invalid address", func(r *dtos.AccessRequestCreateDto) { r.ResearcherAddress = "0x123" }, true, "researcher_address"},
+		{"Missing purpose", func(r *dtos.AccessRequestCreateDto) { r.Purpose = "  " }, true, "Purpose is required"},
+		{"Purpose too long", func(r *dtos.AccessRequestCreateDto) { r.Purpose = strings.Repeat("a", 1001) }, true, "cannot exceed"},
+		{"Zero duration", func(r *dtos.AccessRequestCreateDto) { r.DurationDays = 0 }, true, "duration_days"},
+		{"Duration too long", func(r *dtos.AccessRequestCreateDto) { r.DurationDays = 366 }, true, "duration_days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid
+			tt.modify(&request)
+
+			err := ValidateAccessRequest(request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAccessRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateAccessRequest() error = %v, expected to contain %v", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package ipfs
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"strings"
+)
+
+// Chunking and DAG layout used by `ipfs add --cid-version=1`, which is what
+// Pinata and Kubo run for CIDv1 uploads: 256 KiB raw leaves under a balanced
+// UnixFS tree of at most 174 links per node.
+const (
+	ChunkSize       = 256 * 1024
+	maxLinksPerNode = 174
+)
+
+// Multicodec codes of the two block types a UnixFS file is made of.
+const (
+	codecRaw    = 0x55
+	codecDagPB  = 0x70
+	hashSHA2256 = 0x12
+)
+
+var cidBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// CIDWriter computes the CIDv1 of the bytes written to it without keeping
+// more than one chunk in memory. Only the leaf hashes are retained to build
+// the DAG root in CID.
+type CIDWriter struct {
+	chunk  []byte
+	leaves []dagLink
+	size   int64
+}
+
+// dagLink is a finished block as its parent links to it.
+type dagLink struct {
+	cid      []byte
+	tsize    uint64 // block size plus the tsize of every block below it
+	fileSize uint64 // bytes of file content below it
+}
+
+func NewCIDWriter() *CIDWriter {
+	return &CIDWriter{chunk: make([]byte, 0, ChunkSize)}
+}
+
+func (w *CIDWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(ChunkSize-len(w.chunk), len(p))
+		w.chunk = append(w.chunk, p[:take]...)
+		p = p[take:]
+		if len(w.chunk) == ChunkSize {
+			w.flush()
+		}
+	}
+	w.size += int64(n)
+	return n, nil
+}
+
+// Size returns the number of bytes written so far.
+func (w *CIDWriter) Size() int64 {
+	return w.size
+}
+
+// CID returns the base32 CIDv1 of everything written so far. A file of at most
+// one chunk is a single raw block, larger files get a dag-pb root.
+func (w *CIDWriter) CID() string {
+	leaves := w.leaves
+	if len(w.chunk) > 0 || len(leaves) == 0 {
+		leaves = append(leaves[:len(leaves):len(leaves)], rawLeaf(w.chunk))
+	}
+	return formatCID(buildBalanced(leaves).cid)
+}
+
+func (w *CIDWriter) flush() {
+	w.leaves = append(w.leaves, rawLeaf(w.chunk))
+	w.chunk = w.chunk[:0]
+}
+
+func rawLeaf(data []byte) dagLink {
+	return dagLink{
+		cid:      cidBytes(codecRaw, data),
+		tsize:    uint64(len(data)),
+		fileSize: uint64(len(data)),
+	}
+}
+
+// buildBalanced mirrors the balanced layout of the UnixFS importer: the tree
+// grows one level at a time, the previous root becoming the first child of the
+// new one, and each level is filled depth first.
+func buildBalanced(leaves []dagLink) dagLink {
+	root, rest := leaves[0], leaves[1:]
+	for depth := 1; len(rest) > 0; depth++ {
+		root, rest = fillNode([]dagLink{root}, rest, depth)
+	}
+	return root
+}
+
+func fillNode(children []dagLink, rest []dagLink, depth int) (dagLink, []dagLink) {
+	for len(children) < maxLinksPerNode && len(rest) > 0 {
+		if depth == 1 {
+			children = append(children, rest[0])
+			rest = rest[1:]
+			continue
+		}
+
+		var child dagLink
+		child, rest = fillNode(nil, rest, depth-1)
+		children = append(children, child)
+	}
+	return fileNode(children), rest
+}
+
+// fileNode encodes a dag-pb node holding a UnixFS file entry over children.
+// dag-pb puts Links (field 2) before Data (field 1) and every link carries an
+// empty Name, as go-ipfs writes them.
+func fileNode(children []dagLink) dagLink {
+	var fileSize, tsize uint64
+	unixfs := appendVarintField(nil, 1, 2) // Type: File
+	for _, child := range children {
+		fileSize += child.fileSize
+	}
+	unixfs = appendVarintField(unixfs, 3, fileSize)
+	for _, child := range children {
+		unixfs = appendVarintField(unixfs, 4, child.fileSize)
+	}
+
+	var node []byte
+	for _, child := range children {
+		link := appendBytesField(nil, 1, child.cid)
+		link = appendBytesField(link, 2, nil)
+		link = appendVarintField(link, 3, child.tsize)
+		node = appendBytesField(node, 2, link)
+		tsize += child.tsize
+	}
+	node = appendBytesField(node, 1, unixfs)
+
+	return dagLink{
+		cid:      cidBytes(codecDagPB, node),
+		tsize:    uint64(len(node)) + tsize,
+		fileSize: fileSize,
+	}
+}
+
+func cidBytes(codec uint64, block []byte) []byte {
+	digest := sha256.Sum256(block)
+	b := binary.AppendUvarint(nil, 1) // CID version
+	b = binary.AppendUvarint(b, codec)
+	b = binary.AppendUvarint(b, hashSHA2256)
+	b = binary.AppendUvarint(b, uint64(len(digest)))
+	return append(b, digest[:]...)
+}
+
+// formatCID renders a binary CID in the multibase base32 form ("b" prefix,
+// lower case) IPFS uses for CIDv1.
+func formatCID(cid []byte) string {
+	return "b" + strings.ToLower(cidBase32.EncodeToString(cid))
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
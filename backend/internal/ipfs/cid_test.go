@@ -0,0 +1,93 @@
+package ipfs
+
+import (
+	"testing"
+)
+
+func TestCIDWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"Empty file", nil, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"},
+		{"Single raw block", []byte("hello world"), "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"},
+		{"Chunked into a dag-pb root", testContent(600000), "bafybeidnbbgqbrz3gieyb37t7qkujewoueki3uaevt6pnnwz2oobye7wty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewCIDWriter()
+			w.Write(tt.content)
+
+			if got := w.CID(); got != tt.want {
+				t.Errorf("CID() = %s, want %s", got, tt.want)
+			}
+			if w.Size() != int64(len(tt.content)) {
+				t.Errorf("Size() = %d, want %d", w.Size(), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestCIDWriter_WriteBoundaries(t *testing.T) {
+	content := testContent(3*ChunkSize + 17)
+
+	whole := NewCIDWriter()
+	whole.Write(content)
+
+	// Writes that straddle chunk boundaries must not change the chunking.
+	split := NewCIDWriter()
+	for rest := content; len(rest) > 0; {
+		n := min(len(rest), 100003)
+		split.Write(rest[:n])
+		rest = rest[n:]
+	}
+
+	first := whole.CID()
+	if first != split.CID() {
+		t.Errorf("CID() differs with write sizes: %s vs %s", first, split.CID())
+	}
+	if whole.CID() != first {
+		t.Error("CID() is not stable across calls")
+	}
+}
+
+func TestCIDWriter_ExactChunk(t *testing.T) {
+	// Exactly one chunk is still a single raw block, with no empty trailing
+	// chunk turning it into a dag-pb root.
+	w := NewCIDWriter()
+	w.Write(testContent(ChunkSize))
+
+	if got := w.CID(); got[:4] != "bafk" {
+		t.Errorf("Expected a raw CID for a single chunk, got %s", got)
+	}
+}
+
+func TestBuildBalanced_Depth(t *testing.T) {
+	leaves := make([]dagLink, maxLinksPerNode+1)
+	for i := range leaves {
+		leaves[i] = rawLeaf([]byte{byte(i)})
+	}
+
+	root := buildBalanced(leaves)
+	if root.fileSize != uint64(len(leaves)) {
+		t.Errorf("Expected root file size %d, got %d", len(leaves), root.fileSize)
+	}
+
+	// One leaf over the link limit: a full first level node plus a second
+	// node holding the last leaf.
+	full := fileNode(leaves[:maxLinksPerNode])
+	want := fileNode([]dagLink{full, fileNode(leaves[maxLinksPerNode:])})
+	if string(root.cid) != string(want.cid) {
+		t.Errorf("Unexpected layout for %d leaves", len(leaves))
+	}
+}
+
+func testContent(n int) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i*7 + i/251)
+	}
+	return content
+}
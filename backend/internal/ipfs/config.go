@@ -8,6 +8,7 @@ import (
 type Client struct {
 	APIKey    string
 	APISecret string
+	BaseURL   string
 	HTTP      *http.Client
 }
 
@@ -29,4 +30,17 @@ type PinataOptions struct {
 type LimitedReader struct {
 	io.Reader
 	remaining int64
+	read      int64
+}
+
+// PinataPin is a row of Pinata's pin list.
+type PinataPin struct {
+	IpfsPinHash string `json:"ipfs_pin_hash"`
+	Size        int64  `json:"size"`
+	DatePinned  string `json:"date_pinned"`
+}
+
+type PinataPinList struct {
+	Count int         `json:"count"`
+	Rows  []PinataPin `json:"rows"`
 }
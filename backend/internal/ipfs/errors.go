@@ -0,0 +1,68 @@
+package ipfs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Upload failure kinds. Errors returned by uploads wrap one of them so
+// callers can pick a response with errors.Is.
+var (
+	ErrFileTooLarge = errors.New("file size exceeds limit")
+	ErrUnauthorized = errors.New("storage provider rejected the credentials")
+	ErrRateLimited  = errors.New("storage provider rate limit exceeded")
+	ErrTransient    = errors.New("storage provider temporarily unavailable")
+)
+
+// PinataError is a failed Pinata call. Err is the failure kind, nil when the
+// status does not map to one.
+type PinataError struct {
+	StatusCode int           // 0 when the request never got a response
+	Message    string        // response body or transport error
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+	Err        error
+}
+
+func (e *PinataError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("pinata request failed: %s", e.Message)
+	}
+	return fmt.Sprintf("pinata request failed (%d): %s", e.StatusCode, e.Message)
+}
+
+func (e *PinataError) Unwrap() error {
+	return e.Err
+}
+
+// errorKind maps a Pinata response status to a failure kind.
+func errorKind(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ErrFileTooLarge
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return ErrTransient
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date. It returns 0 when the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
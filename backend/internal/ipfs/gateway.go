@@ -0,0 +1,125 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultGateway        = "https://gateway.pinata.cloud/ipfs"
+	GatewayHeaderTimeout  = 15 * time.Second
+	maxGatewayErrorLength = 256
+)
+
+var ErrNotFound = errors.New("content not found")
+
+// Gateway reads content from a list of IPFS HTTP gateways, trying each in
+// order until one serves it.
+type Gateway struct {
+	URLs []string
+	HTTP *http.Client
+}
+
+// GatewayResponse is an open response from the gateway that served the
+// content. The caller must close Body.
+type GatewayResponse struct {
+	*http.Response
+	Gateway string
+}
+
+var (
+	gatewayInstance *Gateway
+	gatewayOnce     sync.Once
+)
+
+// GetGateway returns the gateway configured by IPFS_GATEWAYS, a comma
+// separated list of gateway base URLs such as https://ipfs.io/ipfs.
+func GetGateway() *Gateway {
+	gatewayOnce.Do(func() {
+		gatewayInstance = NewGateway(parseGatewayURLs(os.Getenv("IPFS_GATEWAYS")))
+	})
+	return gatewayInstance
+}
+
+func NewGateway(urls []string) *Gateway {
+	return &Gateway{
+		URLs: urls,
+		// No overall timeout, bodies are streamed to the caller for as long
+		// as the download takes. Only waiting for headers is bounded.
+		HTTP: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: GatewayHeaderTimeout,
+			},
+		},
+	}
+}
+
+func parseGatewayURLs(value string) []string {
+	var urls []string
+	for _, u := range strings.Split(value, ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return []string{DefaultGateway}
+	}
+	return urls
+}
+
+// Fetch requests cid from each gateway in turn, forwarding rangeHeader when
+// set. A gateway that fails, times out or does not have the content is
+// skipped. 200, 206 and 416 responses are returned as they are final answers
+// about the content.
+func (g *Gateway) Fetch(ctx context.Context, cid string, rangeHeader string) (*GatewayResponse, error) {
+	if cid == "" || strings.ContainsAny(cid, "/?#") {
+		return nil, fmt.Errorf("invalid cid %q", cid)
+	}
+
+	var errs []error
+	notFound := 0
+	for _, base := range g.URLs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/"+url.PathEscape(cid), nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+
+		resp, err := g.HTTP.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", base, err))
+			continue
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+			return &GatewayResponse{Response: resp, Gateway: base}, nil
+		case http.StatusNotFound:
+			notFound++
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxGatewayErrorLength))
+		resp.Body.Close()
+		errs = append(errs, fmt.Errorf("%s: status %d: %s", base, resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	if notFound > 0 && notFound == len(g.URLs) {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("all gateways failed: %w", errors.Join(errs...))
+}
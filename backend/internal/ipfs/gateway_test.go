@@ -0,0 +1,113 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseGatewayURLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"Not set", "", []string{DefaultGateway}},
+		{"Single", "https://ipfs.io/ipfs/", []string{"https://ipfs.io/ipfs"}},
+		{"List", "https://a.example/ipfs, https://b.example/ipfs,", []string{"https://a.example/ipfs", "https://b.example/ipfs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGatewayURLs(tt.value)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseGatewayURLs() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseGatewayURLs()[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayFetch_Fallback(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	var gotPath, gotRange string
+	serving := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotRange = r.Header.Get("Range")
+		w.Header().Set("Content-Range", "bytes 0-3/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("data"))
+	}))
+	defer serving.Close()
+
+	gateway := NewGateway([]string{failing.URL + "/ipfs", serving.URL + "/ipfs"})
+	resp, err := gateway.Fetch(context.Background(), "bafytestcid", "bytes=0-3")
+	if err != nil {
+		t.Fatalf("Fetch() unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Gateway != serving.URL+"/ipfs" {
+		t.Errorf("Expected content from second gateway, got %s", resp.Gateway)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Errorf("Expected status 206, got %d", resp.StatusCode)
+	}
+	if gotPath != "/ipfs/bafytestcid" {
+		t.Errorf("Expected path /ipfs/bafytestcid, got %s", gotPath)
+	}
+	if gotRange != "bytes=0-3" {
+		t.Errorf("Expected Range header to be forwarded, got %q", gotRange)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "data" {
+		t.Errorf("Expected body 'data', got %q", body)
+	}
+}
+
+func TestGatewayFetch_Errors(t *testing.T) {
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer notFound.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	t.Run("not found everywhere", func(t *testing.T) {
+		gateway := NewGateway([]string{notFound.URL, notFound.URL})
+		_, err := gateway.Fetch(context.Background(), "bafytestcid", "")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("all gateways fail", func(t *testing.T) {
+		gateway := NewGateway([]string{notFound.URL, failing.URL})
+		_, err := gateway.Fetch(context.Background(), "bafytestcid", "")
+		if err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected gateway failure, got %v", err)
+		}
+	})
+
+	t.Run("invalid cid", func(t *testing.T) {
+		gateway := NewGateway([]string{notFound.URL})
+		if _, err := gateway.Fetch(context.Background(), "../pins", ""); err == nil {
+			t.Error("Expected error for invalid cid")
+		}
+	})
+}
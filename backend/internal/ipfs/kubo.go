@@ -0,0 +1,203 @@
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KuboStorage pins on a Kubo node through its HTTP RPC API (/api/v0), such as
+// a local `ipfs daemon`.
+type KuboStorage struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+type kuboAddResponse struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+	Size string `json:"Size"`
+}
+
+type kuboFileStat struct {
+	Hash string `json:"Hash"`
+	Size int64  `json:"Size"`
+}
+
+type kuboPinList struct {
+	Keys map[string]struct {
+		Type string `json:"Type"`
+	} `json:"Keys"`
+}
+
+type kuboError struct {
+	Message string `json:"Message"`
+}
+
+func NewKuboStorage(baseURL string) *KuboStorage {
+	return &KuboStorage{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		// No overall timeout, Get streams the content for as long as the
+		// caller reads it. Calls are bounded by their context instead.
+		HTTP: &http.Client{},
+	}
+}
+
+// Put adds the content with the options `ipfs add --cid-version=1` uses, so
+// the CID matches the one Pinata gives the same bytes.
+func (s *KuboStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	limitedReader := newLimitedReader(r, MaxFileSize)
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(part, limitedReader); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+
+	query := url.Values{}
+	query.Set("cid-version", "1")
+	query.Set("pin", "true")
+	resp, err := s.call(ctx, "add", query, pr, mw.FormDataContentType())
+	if err != nil {
+		pr.CloseWithError(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var added kuboAddResponse
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		return nil, fmt.Errorf("decode kubo add response: %w", err)
+	}
+
+	return &PutResult{CID: added.Hash, Size: limitedReader.BytesRead()}, nil
+}
+
+func (s *KuboStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.call(ctx, "cat", url.Values{"arg": {cid}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *KuboStorage) Unpin(ctx context.Context, cid string) error {
+	if err := checkCID(cid); err != nil {
+		return err
+	}
+
+	resp, err := s.call(ctx, "pin/rm", url.Values{"arg": {cid}}, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *KuboStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.call(ctx, "pin/ls", url.Values{"arg": {cid}, "type": {"recursive"}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	// Pinned blocks are local, so the stat does not reach out to the network.
+	resp, err = s.call(ctx, "files/stat", url.Values{"arg": {"/ipfs/" + cid}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var stat kuboFileStat
+	if err := json.NewDecoder(resp.Body).Decode(&stat); err != nil {
+		return nil, fmt.Errorf("decode kubo stat response: %w", err)
+	}
+	return &PinStat{CID: cid, Size: stat.Size}, nil
+}
+
+// ListPins lists the node's recursive pins, which is how Put pins content.
+func (s *KuboStorage) ListPins(ctx context.Context) (map[string]bool, error) {
+	resp, err := s.call(ctx, "pin/ls", url.Values{"type": {"recursive"}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list kuboPinList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode kubo pin list: %w", err)
+	}
+
+	pins := make(map[string]bool, len(list.Keys))
+	for cid := range list.Keys {
+		pins[cid] = true
+	}
+	return pins, nil
+}
+
+func (s *KuboStorage) Ping(ctx context.Context) error {
+	resp, err := s.call(ctx, "version", nil, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// call POSTs to an RPC command, as Kubo requires for every command, and turns
+// error responses into errors. Kubo answers "not pinned" with a 500, which is
+// mapped to ErrNotFound.
+func (s *KuboStorage) call(ctx context.Context, command string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	endpoint := s.BaseURL + "/api/v0/" + command
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var kuboErr kuboError
+	if json.Unmarshal(raw, &kuboErr) != nil || kuboErr.Message == "" {
+		kuboErr.Message = strings.TrimSpace(string(raw))
+	}
+	if strings.Contains(kuboErr.Message, "not pinned") {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("kubo %s failed (%d): %s", command, resp.StatusCode, kuboErr.Message)
+}
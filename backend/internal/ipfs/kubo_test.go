@@ -0,0 +1,108 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKuboStoragePut(t *testing.T) {
+	var gotQuery, gotContent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v0/add" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		gotQuery = r.URL.RawQuery
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected file part: %v", err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		gotContent = string(content)
+
+		w.Write([]byte(`{"Name":"scan","Hash":"bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e","Size":"19"}`))
+	}))
+	defer server.Close()
+
+	storage := NewKuboStorage(server.URL)
+	res, err := storage.Put(context.Background(), strings.NewReader("hello world"), "scan", nil)
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if res.CID != "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e" {
+		t.Errorf("Unexpected CID %s", res.CID)
+	}
+	if res.Size != 11 {
+		t.Errorf("Expected size 11, got %d", res.Size)
+	}
+	if gotContent != "hello world" {
+		t.Errorf("Expected content to be uploaded, got %q", gotContent)
+	}
+	if !strings.Contains(gotQuery, "cid-version=1") || !strings.Contains(gotQuery, "pin=true") {
+		t.Errorf("Expected CIDv1 pinned add, got query %q", gotQuery)
+	}
+}
+
+func TestKuboStorageStat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/pin/ls":
+			if r.URL.Query().Get("arg") == "bafymissing" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"Message":"path '/ipfs/bafymissing' is not pinned","Code":0,"Type":"error"}`))
+				return
+			}
+			w.Write([]byte(`{"Keys":{"bafypinned":{"Type":"recursive"}}}`))
+		case "/api/v0/files/stat":
+			w.Write([]byte(`{"Hash":"bafypinned","Size":600000,"CumulativeSize":600158}`))
+		case "/api/v0/version":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	storage := NewKuboStorage(server.URL)
+
+	stat, err := storage.Stat(context.Background(), "bafypinned")
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if stat.Size != 600000 {
+		t.Errorf("Expected file size 600000, got %d", stat.Size)
+	}
+
+	if _, err := storage.Stat(context.Background(), "bafymissing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := storage.Ping(context.Background()); err == nil {
+		t.Error("Expected ping error from an unavailable node")
+	}
+}
+
+func TestKuboStorageListPins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v0/pin/ls" || r.URL.Query().Get("type") != "recursive" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"Keys":{"bafyone":{"Type":"recursive"},"bafytwo":{"Type":"recursive"}}}`))
+	}))
+	defer server.Close()
+
+	pins, err := NewKuboStorage(server.URL).ListPins(context.Background())
+	if err != nil {
+		t.Fatalf("ListPins() unexpected error: %v", err)
+	}
+	if len(pins) != 2 || !pins["bafyone"] || !pins["bafytwo"] {
+		t.Errorf("Unexpected pins %v", pins)
+	}
+}
@@ -0,0 +1,135 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage keeps content in a directory, one file per CID. CIDs are
+// computed the way Pinata and Kubo compute them, so records created against it
+// carry real CIDs, but nothing is published to the IPFS network.
+type LocalStorage struct {
+	Dir string
+}
+
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create local storage directory: %w", err)
+	}
+	return &LocalStorage{Dir: dir}, nil
+}
+
+// Put writes the content to a temporary file while computing its CID, then
+// renames it into place, so a failed upload never leaves a partial object.
+func (s *LocalStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cidWriter := NewCIDWriter()
+	if _, err := io.Copy(io.MultiWriter(tmp, cidWriter), newLimitedReader(contextReader{ctx, r}, MaxFileSize)); err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	cid := cidWriter.CID()
+	if err := os.Rename(tmp.Name(), s.path(cid)); err != nil {
+		return nil, err
+	}
+
+	return &PutResult{CID: cid, Size: cidWriter.Size()}, nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(s.path(cid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *LocalStorage) Unpin(ctx context.Context, cid string) error {
+	if err := checkCID(cid); err != nil {
+		return err
+	}
+
+	err := os.Remove(s.path(cid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(s.path(cid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PinStat{CID: cid, Size: info.Size()}, nil
+}
+
+// ListPins lists the stored objects, skipping uploads still in progress.
+func (s *LocalStorage) ListPins(ctx context.Context) (map[string]bool, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pins := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && checkCID(entry.Name()) == nil {
+			pins[entry.Name()] = true
+		}
+	}
+	return pins, nil
+}
+
+func (s *LocalStorage) Ping(ctx context.Context) error {
+	info, err := os.Stat(s.Dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.Dir)
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(cid string) string {
+	return filepath.Join(s.Dir, cid)
+}
+
+// contextReader stops a copy once ctx is done, such as when the client
+// uploading the content goes away.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
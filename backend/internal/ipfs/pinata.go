@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"math/rand/v2"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -19,8 +21,13 @@ const (
 	MaxFileSize    = 10 * 1024 * 1024 // 10MB
 	DefaultTimeout = 2 * time.Minute
 	MaxRetries     = 3
+	maxRetryWait   = 30 * time.Second
 )
 
+// retryBaseDelay is the backoff before the first retry, a variable so tests
+// can shorten it.
+var retryBaseDelay = time.Second
+
 var (
 	clientInstance *Client
 	clientOnce     sync.Once
@@ -51,6 +58,7 @@ func NewClient(apiKey, apiSecret string) *Client {
 	return &Client{
 		APIKey:    apiKey,
 		APISecret: apiSecret,
+		BaseURL:   PinataBaseURL,
 		HTTP: &http.Client{
 			Timeout: DefaultTimeout,
 		},
@@ -64,27 +72,73 @@ func (c *Client) validate() error {
 	return nil
 }
 
-func (c *Client) doWithRetry(req *http.Request, maxRetries int) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+// doWithRetry sends the request built by newRequest, retrying transport
+// errors, 429 and 5xx responses up to maxRetries times. A fresh request is
+// built for every attempt as a body cannot be sent twice. The wait between
+// attempts is the server's Retry-After when given, exponential backoff with
+// jitter otherwise, and is cut short by ctx. Any other response is returned
+// for the caller to handle.
+func (c *Client) doWithRetry(ctx context.Context, newRequest func() (*http.Request, error), maxRetries int) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		resp, err = c.HTTP.Do(req)
-		if err == nil && resp.StatusCode < 500 {
+		var failure *PinataError
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failure = &PinataError{Message: err.Error(), Err: ErrTransient}
+		} else if kind := errorKind(resp.StatusCode); kind == ErrRateLimited || kind == ErrTransient {
+			failure = newPinataError(resp)
+			resp.Body.Close()
+		} else {
 			return resp, nil
 		}
 
-		if resp != nil {
-			resp.Body.Close()
+		if attempt >= maxRetries {
+			return nil, failure
 		}
 
-		if attempt < maxRetries {
-			backoffDuration := time.Duration(math.Pow(2, float64(attempt))) * time.Second
-			time.Sleep(backoffDuration)
+		wait := failure.RetryAfter
+		if wait == 0 {
+			wait = backoff(attempt)
+		}
+		if wait > maxRetryWait {
+			// Waiting longer would outlast the upload request itself.
+			return nil, failure
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
 	}
+}
 
-	return resp, err
+// backoff returns the wait before retry attempt+1: retryBaseDelay doubled per
+// attempt, with full jitter over its upper half so clients retrying together
+// spread out.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	return d/2 + rand.N(d/2+1)
+}
+
+// newPinataError reads the body of a failed response into a PinataError.
+func newPinataError(resp *http.Response) *PinataError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &PinataError{
+		StatusCode: resp.StatusCode,
+		Message:    string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Err:        errorKind(resp.StatusCode),
+	}
 }
 
 func newLimitedReader(r io.Reader, maxSize int64) *LimitedReader {
@@ -96,7 +150,7 @@ func newLimitedReader(r io.Reader, maxSize int64) *LimitedReader {
 
 func (lr *LimitedReader) Read(p []byte) (n int, err error) {
 	if lr.remaining <= 0 {
-		return 0, fmt.Errorf("file size exceeds limit of %d bytes", MaxFileSize)
+		return 0, fmt.Errorf("%w of %d bytes", ErrFileTooLarge, MaxFileSize)
 	}
 
 	if int64(len(p)) > lr.remaining {
@@ -105,9 +159,17 @@ func (lr *LimitedReader) Read(p []byte) (n int, err error) {
 
 	n, err = lr.Reader.Read(p)
 	lr.remaining -= int64(n)
+	lr.read += int64(n)
 	return n, err
 }
 
+// BytesRead returns the number of bytes read through the limit so far.
+func (lr *LimitedReader) BytesRead() int64 {
+	return lr.read
+}
+
+// StreamToPinata pins the content of fileReader. The content is spooled to a
+// temporary file first so that a retried upload sends it again in full.
 func (c *Client) StreamToPinata(
 	ctx context.Context,
 	fileReader io.Reader,
@@ -120,10 +182,67 @@ func (c *Client) StreamToPinata(
 		return nil, fmt.Errorf("client validation failed: %w", err)
 	}
 
-	limitedReader := newLimitedReader(fileReader, MaxFileSize)
+	spool, size, err := spoolUpload(fileReader)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	url := c.BaseURL + "/pinning/pinFileToIPFS"
+	newRequest := func() (*http.Request, error) {
+		body, contentType := multipartBody(io.NewSectionReader(spool, 0, size), filename, metadata, options)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		// Use multipart writer content-type (includes boundary)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("pinata_api_key", c.APIKey)
+		req.Header.Set("pinata_secret_api_key", c.APISecret)
+		return req, nil
+	}
+
+	resp, err := c.doWithRetry(ctx, newRequest, MaxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("pinata upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("pinata upload failed: %w", newPinataError(resp))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var out PinataResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("decode pinata response: %w; body=%s", err, string(body))
+	}
 
-	const url = PinataBaseURL + "/pinning/pinFileToIPFS"
+	return &out, nil
+}
 
+// spoolUpload copies the upload, at most MaxFileSize bytes, to a temporary
+// file so every retry can send it again. The caller removes the file.
+func spoolUpload(r io.Reader) (*os.File, int64, error) {
+	spool, err := os.CreateTemp("", "pinata-upload-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("create upload spool: %w", err)
+	}
+
+	size, err := io.Copy(spool, newLimitedReader(r, MaxFileSize))
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, 0, err
+	}
+
+	return spool, size, nil
+}
+
+// multipartBody streams a pinFileToIPFS form with content as the file part.
+func multipartBody(content io.Reader, filename string, metadata *PinataMetadata, options *PinataOptions) (io.Reader, string) {
 	// Pipe lets us write multipart data while http.Client reads it
 	pr, pw := io.Pipe()
 	mw := multipart.NewWriter(pw)
@@ -139,7 +258,7 @@ func (c *Client) StreamToPinata(
 			_ = pw.CloseWithError(err)
 			return
 		}
-		if _, err := io.Copy(part, limitedReader); err != nil {
+		if _, err := io.Copy(part, content); err != nil {
 			_ = pw.CloseWithError(err)
 			return
 		}
@@ -171,31 +290,132 @@ func (c *Client) StreamToPinata(
 		}
 	}()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	return pr, mw.FormDataContentType()
+}
+
+// TestAuthentication checks Pinata is reachable and accepts the credentials.
+func (c *Client) TestAuthentication(ctx context.Context) error {
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("client validation failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/data/testAuthentication", nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req.Header.Set("pinata_api_key", c.APIKey)
+	req.Header.Set("pinata_secret_api_key", c.APISecret)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pinata authentication failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Unpin removes cid from the account's pins. It returns ErrNotFound when the
+// account does not pin it.
+func (c *Client) Unpin(ctx context.Context, cid string) error {
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("client validation failed: %w", err)
+	}
+	if err := checkCID(cid); err != nil {
+		return err
 	}
 
-	// Use multipart writer content-type (includes boundary)
-	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.BaseURL+"/pinning/unpin/"+cid, nil)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("pinata_api_key", c.APIKey)
 	req.Header.Set("pinata_secret_api_key", c.APISecret)
 
-	resp, err := c.doWithRetry(req, MaxRetries)
+	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed after %d retries: %w", MaxRetries, err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("pinata upload failed (%d): %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pinata unpin failed (%d): %s", resp.StatusCode, string(body))
 	}
 
-	var out PinataResponse
-	if err := json.Unmarshal(body, &out); err != nil {
-		return nil, fmt.Errorf("decode pinata response: %w; body=%s", err, string(body))
+	return nil
+}
+
+// GetPin looks cid up in the account's active pins. It returns ErrNotFound
+// when the account does not pin it.
+func (c *Client) GetPin(ctx context.Context, cid string) (*PinataPin, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
 	}
 
-	return &out, nil
+	query := url.Values{}
+	query.Set("hashContains", cid)
+	query.Set("status", "pinned")
+	list, err := c.pinList(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// hashContains is a substring match.
+	for _, pin := range list.Rows {
+		if pin.IpfsPinHash == cid {
+			return &pin, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListPins returns a page of the account's active pins, oldest first. Count
+// is the total number of active pins.
+func (c *Client) ListPins(ctx context.Context, offset int, limit int) (*PinataPinList, error) {
+	query := url.Values{}
+	query.Set("status", "pinned")
+	query.Set("pageOffset", strconv.Itoa(offset))
+	query.Set("pageLimit", strconv.Itoa(limit))
+	query.Set("sortBy", "date_pinned")
+	query.Set("sortOrder", "ASC")
+	return c.pinList(ctx, query)
+}
+
+func (c *Client) pinList(ctx context.Context, query url.Values) (*PinataPinList, error) {
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("client validation failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/data/pinList?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("pinata_api_key", c.APIKey)
+	req.Header.Set("pinata_secret_api_key", c.APISecret)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("pinata pin list failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var list PinataPinList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode pinata pin list: %w", err)
+	}
+	return &list, nil
 }
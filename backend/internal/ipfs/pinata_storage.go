@@ -0,0 +1,77 @@
+package ipfs
+
+import (
+	"context"
+	"io"
+)
+
+// pinListPageSize is the largest page Pinata's pin list serves.
+const pinListPageSize = 1000
+
+// PinataStorage pins through the Pinata API and reads content back through
+// the public gateways, Pinata having no download API of its own.
+type PinataStorage struct {
+	Client  *Client
+	Gateway *Gateway
+}
+
+func NewPinataStorage(client *Client, gateway *Gateway) *PinataStorage {
+	return &PinataStorage{
+		Client:  client,
+		Gateway: gateway,
+	}
+}
+
+func (s *PinataStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	counter := newLimitedReader(r, MaxFileSize)
+	res, err := s.Client.StreamToPinata(ctx, counter, name,
+		&PinataMetadata{Name: name, Keyvalues: meta},
+		&PinataOptions{CidVersion: 1},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PutResult{CID: res.IpfsHash, Size: counter.BytesRead()}, nil
+}
+
+func (s *PinataStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	resp, err := s.Gateway.Fetch(ctx, cid, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *PinataStorage) Unpin(ctx context.Context, cid string) error {
+	return s.Client.Unpin(ctx, cid)
+}
+
+func (s *PinataStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	pin, err := s.Client.GetPin(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	return &PinStat{CID: pin.IpfsPinHash, Size: pin.Size}, nil
+}
+
+// ListPins pages through the account's active pins.
+func (s *PinataStorage) ListPins(ctx context.Context) (map[string]bool, error) {
+	pins := make(map[string]bool)
+	for offset := 0; ; offset += pinListPageSize {
+		page, err := s.Client.ListPins(ctx, offset, pinListPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, pin := range page.Rows {
+			pins[pin.IpfsPinHash] = true
+		}
+		if len(page.Rows) < pinListPageSize || offset+len(page.Rows) >= page.Count {
+			return pins, nil
+		}
+	}
+}
+
+func (s *PinataStorage) Ping(ctx context.Context) error {
+	return s.Client.TestAuthentication(ctx)
+}
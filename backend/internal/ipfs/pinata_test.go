@@ -2,9 +2,17 @@ package ipfs
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -126,3 +134,294 @@ func TestLimitedReader(t *testing.T) {
 		})
 	}
 }
+
+func TestTestAuthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantError  bool
+	}{
+		{"Authenticated", http.StatusOK, false},
+		{"Invalid credentials", http.StatusUnauthorized, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/data/testAuthentication" {
+					t.Errorf("Unexpected path %s", r.URL.Path)
+				}
+				if r.Header.Get("pinata_api_key") != "test-key" {
+					t.Error("Expected pinata_api_key header")
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client := NewClient("test-key", "test-secret")
+			client.BaseURL = server.URL
+
+			err := client.TestAuthentication(context.Background())
+			if (err != nil) != tt.wantError {
+				t.Errorf("TestAuthentication() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestUnpin(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantError  bool
+		notFound   bool
+	}{
+		{"Unpinned", http.StatusOK, false, false},
+		{"Not pinned", http.StatusNotFound, true, true},
+		{"Server error", http.StatusInternalServerError, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != "/pinning/unpin/bafytestcid" {
+					t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client := NewClient("test-key", "test-secret")
+			client.BaseURL = server.URL
+
+			err := client.Unpin(context.Background(), "bafytestcid")
+			if (err != nil) != tt.wantError {
+				t.Errorf("Unpin() error = %v, wantError %v", err, tt.wantError)
+			}
+			if errors.Is(err, ErrNotFound) != tt.notFound {
+				t.Errorf("Unpin() error = %v, notFound %v", err, tt.notFound)
+			}
+		})
+	}
+}
+
+func TestGetPin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/pinList" || r.URL.Query().Get("status") != "pinned" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		// hashContains matches substrings, so a longer CID comes back too.
+		w.Write([]byte(`{"count":2,"rows":[
+			{"ipfs_pin_hash":"bafytestcidlonger","size":10},
+			{"ipfs_pin_hash":"bafytestcid","size":42}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", "test-secret")
+	client.BaseURL = server.URL
+
+	pin, err := client.GetPin(context.Background(), "bafytestcid")
+	if err != nil {
+		t.Fatalf("GetPin() unexpected error: %v", err)
+	}
+	if pin.Size != 42 {
+		t.Errorf("Expected the exact CID match, got %+v", pin)
+	}
+
+	if _, err := client.GetPin(context.Background(), "bafyother"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPinataStorageListPins(t *testing.T) {
+	total := pinListPageSize + 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/data/pinList" || query.Get("status") != "pinned" || query.Get("pageLimit") != strconv.Itoa(pinListPageSize) {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		offset, _ := strconv.Atoi(query.Get("pageOffset"))
+
+		list := PinataPinList{Count: total}
+		for i := offset; i < min(offset+pinListPageSize, total); i++ {
+			list.Rows = append(list.Rows, PinataPin{IpfsPinHash: fmt.Sprintf("bafypin%d", i)})
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", "test-secret")
+	client.BaseURL = server.URL
+
+	pins, err := NewPinataStorage(client, nil).ListPins(context.Background())
+	if err != nil {
+		t.Fatalf("ListPins() unexpected error: %v", err)
+	}
+	if len(pins) != total {
+		t.Errorf("Expected %d pins, got %d", total, len(pins))
+	}
+	if !pins["bafypin0"] || !pins[fmt.Sprintf("bafypin%d", total-1)] {
+		t.Error("Expected the first and last pins to be listed")
+	}
+}
+
+func TestStreamToPinata_RetryResendsBody(t *testing.T) {
+	defer setRetryBaseDelay(time.Millisecond)()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Attempt %d: expected file part: %v", attempts, err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if string(content) != "ciphertext" {
+			t.Errorf("Attempt %d: expected full body, got %q", attempts, content)
+		}
+
+		if attempts == 1 {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"IpfsHash":"bafytestcid","PinSize":10}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", "test-secret")
+	client.BaseURL = server.URL
+
+	res, err := client.StreamToPinata(context.Background(), strings.NewReader("ciphertext"), "scan", nil, nil)
+	if err != nil {
+		t.Fatalf("StreamToPinata() unexpected error: %v", err)
+	}
+	if res.IpfsHash != "bafytestcid" {
+		t.Errorf("Expected bafytestcid, got %s", res.IpfsHash)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestStreamToPinata_Errors(t *testing.T) {
+	defer setRetryBaseDelay(time.Millisecond)()
+
+	tests := []struct {
+		name         string
+		statusCode   int
+		retryAfter   string
+		wantErr      error
+		wantAttempts int
+	}{
+		{"Invalid credentials", http.StatusUnauthorized, "", ErrUnauthorized, 1},
+		{"Rejected as too large", http.StatusRequestEntityTooLarge, "", ErrFileTooLarge, 1},
+		{"Rate limited", http.StatusTooManyRequests, "0", ErrRateLimited, MaxRetries + 1},
+		{"Rate limited for too long", http.StatusTooManyRequests, "3600", ErrRateLimited, 1},
+		{"Unavailable", http.StatusServiceUnavailable, "", ErrTransient, MaxRetries + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				io.Copy(io.Discard, r.Body)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client := NewClient("test-key", "test-secret")
+			client.BaseURL = server.URL
+
+			_, err := client.StreamToPinata(context.Background(), strings.NewReader("ciphertext"), "scan", nil, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("StreamToPinata() error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestStreamToPinata_TooLarge(t *testing.T) {
+	client := NewClient("test-key", "test-secret")
+	client.BaseURL = "http://127.0.0.1:0"
+
+	_, err := client.StreamToPinata(context.Background(), bytes.NewReader(make([]byte, MaxFileSize+1)), "scan", nil, nil)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge before any request, got %v", err)
+	}
+}
+
+func TestDoWithRetry_ContextCancelled(t *testing.T) {
+	defer setRetryBaseDelay(10 * time.Second)()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", "test-secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.doWithRetry(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	}, MaxRetries)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected backoff to stop when the context is done")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"Absent", "", 0},
+		{"Seconds", "5", 5 * time.Second},
+		{"HTTP date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"Date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"Invalid", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer setRetryBaseDelay(time.Second)()
+
+	for attempt := range 3 {
+		ceiling := time.Second << attempt
+		for range 20 {
+			if d := backoff(attempt); d < ceiling/2 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+}
+
+func setRetryBaseDelay(d time.Duration) func() {
+	previous := retryBaseDelay
+	retryBaseDelay = d
+	return func() { retryBaseDelay = previous }
+}
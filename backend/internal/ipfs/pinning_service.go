@@ -0,0 +1,248 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPinWait               = 2 * time.Minute
+	pinStatusPoll                = 2 * time.Second
+	pinningServicePage           = 1000 // the largest page the API allows
+	maxPinningServiceErrorLength = 1024
+)
+
+// ErrPinPending is returned by PinningServiceStorage.Put when the service
+// accepted the pin but had not fetched the content by the end of the wait.
+var ErrPinPending = errors.New("pinning service has not finished pinning")
+
+// PinningServiceStorage pins through an endpoint implementing the IPFS Pinning
+// Service API, such as web3.storage, Filebase or a self-hosted cluster.
+// Those services pin by CID and fetch the content from the IPFS network, so
+// Put only computes the CID: the content has to be provided by another
+// backend, such as a Kubo node listed in Origins. Reads go through the
+// gateways.
+type PinningServiceStorage struct {
+	Endpoint string
+	Token    string
+	Origins  []string      // multiaddrs of nodes providing the content
+	PinWait  time.Duration // how long Put waits for the pin to complete
+	HTTP     *http.Client
+	Gateway  *Gateway
+}
+
+type pinningServicePin struct {
+	CID     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// pinningServiceStatus is the API's PinStatus object. Status is queued,
+// pinning, pinned or failed.
+type pinningServiceStatus struct {
+	RequestID string            `json:"requestid"`
+	Status    string            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       pinningServicePin `json:"pin"`
+}
+
+type pinningServiceResults struct {
+	Count   int                    `json:"count"`
+	Results []pinningServiceStatus `json:"results"`
+}
+
+type pinningServiceError struct {
+	Error struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	} `json:"error"`
+}
+
+func NewPinningServiceStorage(endpoint string, token string, gateway *Gateway) *PinningServiceStorage {
+	return &PinningServiceStorage{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Token:    token,
+		PinWait:  DefaultPinWait,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+		Gateway:  gateway,
+	}
+}
+
+// Put computes the CID of the content, asks the service to pin it and waits
+// up to PinWait for the pin to complete.
+func (s *PinningServiceStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	cidWriter := NewCIDWriter()
+	if _, err := io.Copy(cidWriter, newLimitedReader(r, MaxFileSize)); err != nil {
+		return nil, err
+	}
+	cid := cidWriter.CID()
+
+	body, err := json.Marshal(pinningServicePin{CID: cid, Name: name, Origins: s.Origins, Meta: meta})
+	if err != nil {
+		return nil, err
+	}
+	var status pinningServiceStatus
+	if err := s.call(ctx, http.MethodPost, "/pins", nil, body, &status); err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, s.PinWait)
+	defer cancel()
+	for status.Status != "pinned" {
+		if status.Status == "failed" {
+			return nil, fmt.Errorf("pinning service failed to pin %s", cid)
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%w %s (%s)", ErrPinPending, cid, status.Status)
+		case <-time.After(pinStatusPoll):
+		}
+
+		if err := s.call(waitCtx, http.MethodGet, "/pins/"+url.PathEscape(status.RequestID), nil, nil, &status); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PutResult{CID: cid, Size: cidWriter.Size()}, nil
+}
+
+func (s *PinningServiceStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	resp, err := s.Gateway.Fetch(ctx, cid, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Unpin removes every pin request for cid, whatever its status.
+func (s *PinningServiceStorage) Unpin(ctx context.Context, cid string) error {
+	if err := checkCID(cid); err != nil {
+		return err
+	}
+
+	query := url.Values{"cid": {cid}, "status": {"queued,pinning,pinned,failed"}}
+	var results pinningServiceResults
+	if err := s.call(ctx, http.MethodGet, "/pins", query, nil, &results); err != nil {
+		return err
+	}
+	if len(results.Results) == 0 {
+		return ErrNotFound
+	}
+
+	for _, pin := range results.Results {
+		err := s.call(ctx, http.MethodDelete, "/pins/"+url.PathEscape(pin.RequestID), nil, nil, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat reports whether the service pins cid. The API does not give sizes, so
+// Size is 0.
+func (s *PinningServiceStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	if err := checkCID(cid); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"cid": {cid}, "status": {"pinned"}, "limit": {"1"}}
+	var results pinningServiceResults
+	if err := s.call(ctx, http.MethodGet, "/pins", query, nil, &results); err != nil {
+		return nil, err
+	}
+	if len(results.Results) == 0 {
+		return nil, ErrNotFound
+	}
+	return &PinStat{CID: cid}, nil
+}
+
+// ListPins pages through the completed pins, newest first, as the API orders
+// them.
+func (s *PinningServiceStorage) ListPins(ctx context.Context) (map[string]bool, error) {
+	pins := make(map[string]bool)
+	query := url.Values{"status": {"pinned"}, "limit": {strconv.Itoa(pinningServicePage)}}
+	for {
+		var results pinningServiceResults
+		if err := s.call(ctx, http.MethodGet, "/pins", query, nil, &results); err != nil {
+			return nil, err
+		}
+		for _, pin := range results.Results {
+			pins[pin.Pin.CID] = true
+		}
+		if len(results.Results) < pinningServicePage {
+			return pins, nil
+		}
+		oldest := results.Results[len(results.Results)-1].Created
+		query.Set("before", oldest.Format(time.RFC3339Nano))
+	}
+}
+
+func (s *PinningServiceStorage) Ping(ctx context.Context) error {
+	return s.call(ctx, http.MethodGet, "/pins", url.Values{"limit": {"1"}}, nil, &pinningServiceResults{})
+}
+
+// call sends a request to the API and decodes the response into out when it
+// is not nil. A 404 is ErrNotFound.
+func (s *PinningServiceStorage) call(ctx context.Context, method string, path string, query url.Values, body []byte, out any) error {
+	endpoint := s.Endpoint + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxPinningServiceErrorLength))
+		message := strings.TrimSpace(string(raw))
+		var apiErr pinningServiceError
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Reason != "" {
+			message = apiErr.Error.Reason
+			if apiErr.Error.Details != "" {
+				message += ": " + apiErr.Error.Details
+			}
+		}
+		return fmt.Errorf("pinning service %s %s failed (%d): %s", method, path, resp.StatusCode, message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode pinning service response: %w", err)
+	}
+	return nil
+}
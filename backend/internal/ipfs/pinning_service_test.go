@@ -0,0 +1,117 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPinningServiceStorage(t *testing.T) {
+	ctx := context.Background()
+	content := testContent(1000)
+	expected := NewCIDWriter()
+	expected.Write(content)
+	cid := expected.CID()
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"reason":"UNAUTHORIZED","details":"bad token"}}`))
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/pins":
+			var pin pinningServicePin
+			if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+				t.Errorf("Invalid pin body: %v", err)
+			}
+			if pin.CID != cid || pin.Name != "scan" || len(pin.Origins) != 1 {
+				t.Errorf("Unexpected pin request %+v", pin)
+			}
+			json.NewEncoder(w).Encode(pinningServiceStatus{RequestID: "req-1", Status: "pinned", Pin: pin})
+
+		case r.Method == http.MethodGet && r.URL.Path == "/pins":
+			query := r.URL.Query()
+			var results pinningServiceResults
+			if query.Get("cid") == "" || query.Get("cid") == cid {
+				results.Results = []pinningServiceStatus{{RequestID: "req-1", Status: "pinned", Pin: pinningServicePin{CID: cid}}}
+			}
+			results.Count = len(results.Results)
+			json.NewEncoder(w).Encode(results)
+
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/pins/"):
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/pins/"))
+			w.WriteHeader(http.StatusAccepted)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	storage := NewPinningServiceStorage(server.URL+"/", "secret", nil)
+	storage.Origins = []string{"/dns4/kubo.example.com/tcp/4001/p2p/12D3KooW"}
+
+	res, err := storage.Put(ctx, bytes.NewReader(content), "scan", nil)
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if res.CID != cid || res.Size != int64(len(content)) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+
+	if _, err := storage.Stat(ctx, cid); err != nil {
+		t.Errorf("Stat() unexpected error: %v", err)
+	}
+	other := NewCIDWriter()
+	other.Write([]byte("other"))
+	if _, err := storage.Stat(ctx, other.CID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown CID, got %v", err)
+	}
+
+	pins, err := storage.ListPins(ctx)
+	if err != nil {
+		t.Fatalf("ListPins() unexpected error: %v", err)
+	}
+	if len(pins) != 1 || !pins[cid] {
+		t.Errorf("Unexpected pins %v", pins)
+	}
+
+	if err := storage.Unpin(ctx, cid); err != nil {
+		t.Fatalf("Unpin() unexpected error: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "req-1" {
+		t.Errorf("Expected request req-1 deleted, got %v", deleted)
+	}
+	if err := storage.Unpin(ctx, other.CID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound unpinning an unknown CID, got %v", err)
+	}
+
+	storage.Token = "wrong"
+	if err := storage.Ping(ctx); err == nil || !strings.Contains(err.Error(), "UNAUTHORIZED: bad token") {
+		t.Errorf("Expected the API's error reason, got %v", err)
+	}
+}
+
+func TestPinningServiceStoragePending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(pinningServiceStatus{RequestID: "req-1", Status: "queued"})
+	}))
+	defer server.Close()
+
+	storage := NewPinningServiceStorage(server.URL, "secret", nil)
+	storage.PinWait = 20 * time.Millisecond
+
+	_, err := storage.Put(context.Background(), bytes.NewReader([]byte("content")), "scan", nil)
+	if !errors.Is(err, ErrPinPending) {
+		t.Errorf("Expected ErrPinPending, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultStragglerWait is how long a replicated Put waits for the remaining
+// providers once the quorum has pinned.
+const DefaultStragglerWait = 30 * time.Second
+
+var ErrQuorumNotReached = errors.New("storage quorum not reached")
+
+// Provider is a named backend of a ReplicatedStorage.
+type Provider struct {
+	Name    string
+	Storage Storage
+}
+
+// ReplicatedStorage pins content to every provider and counts a Put as
+// successful once Quorum of them pinned it. Reads are served by the first
+// provider that has the content.
+type ReplicatedStorage struct {
+	Providers     []Provider
+	Quorum        int
+	StragglerWait time.Duration
+}
+
+// QuorumError is a Put that fewer than Quorum providers pinned. Pinned lists
+// the providers that did.
+type QuorumError struct {
+	Quorum   int
+	Pinned   []string
+	Failures map[string]error
+}
+
+func (e *QuorumError) Error() string {
+	var failures []string
+	for provider, err := range e.Failures {
+		failures = append(failures, fmt.Sprintf("%s: %v", provider, err))
+	}
+	slices.Sort(failures)
+	return fmt.Sprintf("pinned by %d of the %d providers required (%s)",
+		len(e.Pinned), e.Quorum, strings.Join(failures, "; "))
+}
+
+// Unwrap deliberately hides the provider errors: one provider rejecting the
+// content must not make the whole upload look permanently invalid.
+func (e *QuorumError) Unwrap() error {
+	return ErrQuorumNotReached
+}
+
+func NewReplicatedStorage(providers []Provider, quorum int) (*ReplicatedStorage, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("replicated storage needs at least one provider")
+	}
+	if quorum < 1 || quorum > len(providers) {
+		return nil, fmt.Errorf("storage quorum %d must be between 1 and %d", quorum, len(providers))
+	}
+
+	seen := make(map[string]bool)
+	for _, provider := range providers {
+		if seen[provider.Name] {
+			return nil, fmt.Errorf("storage provider %s is listed twice", provider.Name)
+		}
+		seen[provider.Name] = true
+	}
+
+	return &ReplicatedStorage{
+		Providers:     providers,
+		Quorum:        quorum,
+		StragglerWait: DefaultStragglerWait,
+	}, nil
+}
+
+// Provider returns the provider with the given name, or nil.
+func (s *ReplicatedStorage) Provider(name string) Storage {
+	for _, provider := range s.Providers {
+		if provider.Name == name {
+			return provider.Storage
+		}
+	}
+	return nil
+}
+
+func (s *ReplicatedStorage) ProviderNames() []string {
+	names := make([]string, len(s.Providers))
+	for i, provider := range s.Providers {
+		names[i] = provider.Name
+	}
+	return names
+}
+
+// Put spools the content, then pins it to every provider concurrently. Once
+// the quorum has pinned, the others get StragglerWait to finish before they
+// are cancelled. A provider returning a CID other than the one computed
+// locally is unpinned and counted as failed. Replicas lists the providers
+// that pinned the content.
+func (s *ReplicatedStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	spool, err := os.CreateTemp("", "replicated-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("create upload spool: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	cidWriter := NewCIDWriter()
+	size, err := io.Copy(io.MultiWriter(spool, cidWriter), newLimitedReader(r, MaxFileSize))
+	if err != nil {
+		return nil, err
+	}
+	cid := cidWriter.CID()
+
+	putCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		provider string
+		err      error
+	}
+	outcomes := make(chan outcome, len(s.Providers))
+	for _, provider := range s.Providers {
+		go func() {
+			res, err := provider.Storage.Put(putCtx, io.NewSectionReader(spool, 0, size), name, meta)
+			if err == nil && res.CID != cid {
+				if err := provider.Storage.Unpin(ctx, res.CID); err != nil && !errors.Is(err, ErrNotFound) {
+					log.Printf("Error unpinning mismatched %s from %s: %v", res.CID, provider.Name, err)
+				}
+				err = fmt.Errorf("%w: returned %s, computed %s", ErrCIDMismatch, res.CID, cid)
+			}
+			outcomes <- outcome{provider: provider.Name, err: err}
+		}()
+	}
+
+	pinnedBy := make(map[string]bool)
+	failures := make(map[string]error)
+	var straggler <-chan time.Time
+	waiting := false
+	for received := 0; received < len(s.Providers); {
+		select {
+		case o := <-outcomes:
+			received++
+			if o.err != nil {
+				failures[o.provider] = o.err
+			} else {
+				pinnedBy[o.provider] = true
+			}
+			if len(pinnedBy) == s.Quorum && !waiting && received < len(s.Providers) {
+				timer := time.NewTimer(s.StragglerWait)
+				defer timer.Stop()
+				straggler = timer.C
+				waiting = true
+			}
+		case <-straggler:
+			cancel()
+			straggler = nil
+		}
+	}
+
+	// Keep the providers' configured order.
+	var replicas []string
+	for _, provider := range s.Providers {
+		if pinnedBy[provider.Name] {
+			replicas = append(replicas, provider.Name)
+		}
+	}
+	if len(replicas) < s.Quorum {
+		return nil, &QuorumError{Quorum: s.Quorum, Pinned: replicas, Failures: failures}
+	}
+
+	return &PutResult{CID: cid, Size: size, Replicas: replicas}, nil
+}
+
+func (s *ReplicatedStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	var errs []error
+	for _, provider := range s.Providers {
+		content, err := provider.Storage.Get(ctx, cid)
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, ErrNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Unpin removes the content from every provider. It returns ErrNotFound when
+// none of them pinned it.
+func (s *ReplicatedStorage) Unpin(ctx context.Context, cid string) error {
+	var errs []error
+	unpinned := false
+	for _, provider := range s.Providers {
+		err := provider.Storage.Unpin(ctx, cid)
+		switch {
+		case err == nil:
+			unpinned = true
+		case !errors.Is(err, ErrNotFound):
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if !unpinned {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *ReplicatedStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	var errs []error
+	for _, provider := range s.Providers {
+		stat, err := provider.Storage.Stat(ctx, cid)
+		if err == nil {
+			return stat, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, ErrNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ListPins returns the CIDs at least one provider pins. It fails when any
+// provider cannot be listed, rather than report its content as missing.
+func (s *ReplicatedStorage) ListPins(ctx context.Context) (map[string]bool, error) {
+	byProvider, err := s.ProviderPins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pins := make(map[string]bool)
+	for _, providerPins := range byProvider {
+		for cid := range providerPins {
+			pins[cid] = true
+		}
+	}
+	return pins, nil
+}
+
+// ProviderPins lists the pins of every provider, by provider name.
+func (s *ReplicatedStorage) ProviderPins(ctx context.Context) (map[string]map[string]bool, error) {
+	byProvider := make(map[string]map[string]bool, len(s.Providers))
+	for _, provider := range s.Providers {
+		lister, ok := provider.Storage.(PinLister)
+		if !ok {
+			return nil, fmt.Errorf("%s: storage provider cannot list pins", provider.Name)
+		}
+		pins, err := lister.ListPins(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", provider.Name, err)
+		}
+		byProvider[provider.Name] = pins
+	}
+	return byProvider, nil
+}
+
+// Ping fails when fewer than Quorum providers are reachable, as uploads
+// would then fail.
+func (s *ReplicatedStorage) Ping(ctx context.Context) error {
+	var errs []error
+	for _, provider := range s.Providers {
+		if err := provider.Storage.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		}
+	}
+	if len(s.Providers)-len(errs) < s.Quorum {
+		return errors.Join(errs...)
+	}
+	return nil
+}
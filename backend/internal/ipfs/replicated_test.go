@@ -0,0 +1,220 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeStorage fails every Put with err, or blocks until cancelled when err is
+// nil. With cid set, Put succeeds but returns that CID.
+type fakeStorage struct {
+	err      error
+	cid      string
+	unpinned []string
+}
+
+func (s *fakeStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	if s.cid != "" {
+		return &PutResult{CID: s.cid}, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *fakeStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return nil, ErrNotFound
+}
+
+func (s *fakeStorage) Unpin(ctx context.Context, cid string) error {
+	s.unpinned = append(s.unpinned, cid)
+	return nil
+}
+
+func (s *fakeStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	return nil, ErrNotFound
+}
+
+func (s *fakeStorage) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	storage, err := NewLocalStorage(filepath.Join(t.TempDir(), "ipfs"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage() unexpected error: %v", err)
+	}
+	return storage
+}
+
+func TestReplicatedStoragePut(t *testing.T) {
+	ctx := context.Background()
+	content := testContent(300000)
+	expected := NewCIDWriter()
+	expected.Write(content)
+
+	t.Run("Quorum reached", func(t *testing.T) {
+		first, second := newTestLocalStorage(t), newTestLocalStorage(t)
+		wrong := &fakeStorage{cid: "bafkreiwrong"}
+		storage, err := NewReplicatedStorage([]Provider{
+			{Name: "first", Storage: first},
+			{Name: "wrong", Storage: wrong},
+			{Name: "second", Storage: second},
+		}, 2)
+		if err != nil {
+			t.Fatalf("NewReplicatedStorage() unexpected error: %v", err)
+		}
+
+		res, err := storage.Put(ctx, bytes.NewReader(content), "scan", nil)
+		if err != nil {
+			t.Fatalf("Put() unexpected error: %v", err)
+		}
+		if res.CID != expected.CID() || res.Size != int64(len(content)) {
+			t.Errorf("Unexpected result %+v", res)
+		}
+		if !slices.Equal(res.Replicas, []string{"first", "second"}) {
+			t.Errorf("Expected replicas [first second], got %v", res.Replicas)
+		}
+		if !slices.Equal(wrong.unpinned, []string{"bafkreiwrong"}) {
+			t.Errorf("Expected the mismatched pin to be removed, got %v", wrong.unpinned)
+		}
+		if _, err := second.Stat(ctx, res.CID); err != nil {
+			t.Errorf("Expected every provider to hold the content, got %v", err)
+		}
+	})
+
+	t.Run("Quorum not reached", func(t *testing.T) {
+		storage, err := NewReplicatedStorage([]Provider{
+			{Name: "local", Storage: newTestLocalStorage(t)},
+			{Name: "wrong", Storage: &fakeStorage{cid: "bafkreiwrong"}},
+			{Name: "down", Storage: &fakeStorage{err: errors.New("connection refused")}},
+		}, 2)
+		if err != nil {
+			t.Fatalf("NewReplicatedStorage() unexpected error: %v", err)
+		}
+
+		_, err = storage.Put(ctx, bytes.NewReader(content), "scan", nil)
+		var quorumErr *QuorumError
+		if !errors.As(err, &quorumErr) {
+			t.Fatalf("Expected a QuorumError, got %v", err)
+		}
+		if !slices.Equal(quorumErr.Pinned, []string{"local"}) || len(quorumErr.Failures) != 2 {
+			t.Errorf("Unexpected quorum error %+v", quorumErr)
+		}
+		if !errors.Is(err, ErrQuorumNotReached) {
+			t.Error("Expected errors.Is(err, ErrQuorumNotReached)")
+		}
+		if errors.Is(err, ErrCIDMismatch) {
+			t.Error("Expected a provider's mismatch not to surface as the upload's")
+		}
+	})
+
+	t.Run("Stragglers cancelled", func(t *testing.T) {
+		storage, err := NewReplicatedStorage([]Provider{
+			{Name: "local", Storage: newTestLocalStorage(t)},
+			{Name: "slow", Storage: &fakeStorage{}},
+		}, 1)
+		if err != nil {
+			t.Fatalf("NewReplicatedStorage() unexpected error: %v", err)
+		}
+		storage.StragglerWait = 10 * time.Millisecond
+
+		res, err := storage.Put(ctx, bytes.NewReader(content), "scan", nil)
+		if err != nil {
+			t.Fatalf("Put() unexpected error: %v", err)
+		}
+		if !slices.Equal(res.Replicas, []string{"local"}) {
+			t.Errorf("Expected replicas [local], got %v", res.Replicas)
+		}
+	})
+}
+
+func TestReplicatedStorageReads(t *testing.T) {
+	ctx := context.Background()
+	empty, holder := newTestLocalStorage(t), newTestLocalStorage(t)
+	storage, err := NewReplicatedStorage([]Provider{
+		{Name: "empty", Storage: empty},
+		{Name: "holder", Storage: holder},
+	}, 1)
+	if err != nil {
+		t.Fatalf("NewReplicatedStorage() unexpected error: %v", err)
+	}
+
+	content := testContent(1000)
+	res, err := holder.Put(ctx, bytes.NewReader(content), "scan", nil)
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	reader, err := storage.Get(ctx, res.CID)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, content) {
+		t.Error("Expected Get to fall back to the provider holding the content")
+	}
+
+	pins, err := storage.ProviderPins(ctx)
+	if err != nil {
+		t.Fatalf("ProviderPins() unexpected error: %v", err)
+	}
+	if len(pins["empty"]) != 0 || !pins["holder"][res.CID] {
+		t.Errorf("Unexpected provider pins %v", pins)
+	}
+
+	if err := storage.Unpin(ctx, res.CID); err != nil {
+		t.Fatalf("Unpin() unexpected error: %v", err)
+	}
+	if err := storage.Unpin(ctx, res.CID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound unpinning twice, got %v", err)
+	}
+}
+
+func TestNewReplicatedStorage(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_DIR", t.TempDir())
+	t.Setenv("KUBO_API_URL", "http://127.0.0.1:5001")
+
+	tests := []struct {
+		name      string
+		providers string
+		quorum    string
+		want      int
+		wantErr   bool
+	}{
+		{"Majority by default", "local, Kubo", "", 2, false},
+		{"Explicit quorum", "local,kubo", "1", 1, false},
+		{"Quorum too large", "local,kubo", "3", 0, true},
+		{"Invalid quorum", "local,kubo", "all", 0, true},
+		{"Duplicate provider", "local,local", "", 0, true},
+		{"Unknown provider", "local,s3", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := newReplicatedStorage(tt.providers, tt.quorum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newReplicatedStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if storage.Quorum != tt.want {
+				t.Errorf("Expected quorum %d, got %d", tt.want, storage.Quorum)
+			}
+			if !slices.Equal(storage.ProviderNames(), []string{"local", "kubo"}) {
+				t.Errorf("Unexpected providers %v", storage.ProviderNames())
+			}
+		})
+	}
+}
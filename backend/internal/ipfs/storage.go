@@ -0,0 +1,167 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultKuboURL         = "http://127.0.0.1:5001"
+	DefaultLocalStorageDir = "data/ipfs"
+)
+
+// Storage pins content and reads it back by CID. Implementations return
+// ErrNotFound when the CID is not pinned.
+type Storage interface {
+	// Put pins the content of r, at most MaxFileSize bytes, as a CIDv1.
+	// name and meta are attached to the pin where the backend supports it.
+	Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error)
+	// Get opens the pinned content. The caller must close it.
+	Get(ctx context.Context, cid string) (io.ReadCloser, error)
+	Unpin(ctx context.Context, cid string) error
+	Stat(ctx context.Context, cid string) (*PinStat, error)
+	// Ping checks the backend is reachable, for the readiness probe.
+	Ping(ctx context.Context) error
+}
+
+// PinLister is implemented by backends that can list every pin in a few
+// calls, which beats a Stat per CID when checking many records.
+type PinLister interface {
+	// ListPins returns the CIDs the backend pins.
+	ListPins(ctx context.Context) (map[string]bool, error)
+}
+
+type PutResult struct {
+	CID      string
+	Size     int64    // bytes of content, not of the DAG
+	Replicas []string // providers that pinned it, set by ReplicatedStorage
+}
+
+type PinStat struct {
+	CID  string
+	Size int64
+}
+
+var (
+	storageInstance Storage
+	storageOnce     sync.Once
+	storageErr      error
+)
+
+// GetStorage returns the backend selected by STORAGE_BACKEND: pinata (the
+// default), kubo for a Kubo node's RPC API at KUBO_API_URL, local for a
+// content-addressed directory at LOCAL_STORAGE_DIR, or pinning_service for an
+// IPFS Pinning Service API endpoint at PINNING_SERVICE_URL. When
+// STORAGE_PROVIDERS lists several of them, content is replicated to all and
+// uploads need STORAGE_QUORUM of them, a majority by default.
+func GetStorage() (Storage, error) {
+	storageOnce.Do(func() {
+		if providers := os.Getenv("STORAGE_PROVIDERS"); providers != "" {
+			storageInstance, storageErr = newReplicatedStorage(providers, os.Getenv("STORAGE_QUORUM"))
+			return
+		}
+		storageInstance, storageErr = newStorage(os.Getenv("STORAGE_BACKEND"))
+	})
+	if storageErr != nil {
+		return nil, storageErr
+	}
+	return storageInstance, nil
+}
+
+func newStorage(backend string) (Storage, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", "pinata":
+		client, err := GetClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewPinataStorage(client, GetGateway()), nil
+
+	case "kubo":
+		return NewKuboStorage(getEnv("KUBO_API_URL", DefaultKuboURL)), nil
+
+	case "local":
+		return NewLocalStorage(getEnv("LOCAL_STORAGE_DIR", DefaultLocalStorageDir))
+
+	case "pinning_service":
+		endpoint := os.Getenv("PINNING_SERVICE_URL")
+		if endpoint == "" {
+			return nil, fmt.Errorf("PINNING_SERVICE_URL is required for the pinning_service backend")
+		}
+		storage := NewPinningServiceStorage(endpoint, os.Getenv("PINNING_SERVICE_TOKEN"), GetGateway())
+		storage.Origins = splitList(os.Getenv("PINNING_SERVICE_ORIGINS"))
+		return storage, nil
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q, expected pinata, kubo, local or pinning_service", backend)
+}
+
+// ServedByGateway reports whether the backend reads content back through the
+// configured gateways, so a caller falling back to them need not ask it first.
+func ServedByGateway(storage Storage) bool {
+	switch storage.(type) {
+	case *PinataStorage, *PinningServiceStorage:
+		return true
+	}
+	return false
+}
+
+// newReplicatedStorage builds a ReplicatedStorage over a comma separated
+// list of backends. An empty quorum means a majority of them.
+func newReplicatedStorage(list string, quorumValue string) (*ReplicatedStorage, error) {
+	var providers []Provider
+	for _, name := range splitList(list) {
+		name = strings.ToLower(name)
+		storage, err := newStorage(name)
+		if err != nil {
+			return nil, fmt.Errorf("storage provider %s: %w", name, err)
+		}
+		providers = append(providers, Provider{Name: name, Storage: storage})
+	}
+
+	quorum := len(providers)/2 + 1
+	if quorumValue != "" {
+		var err error
+		quorum, err = strconv.Atoi(quorumValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_QUORUM %q", quorumValue)
+		}
+	}
+	return NewReplicatedStorage(providers, quorum)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnv(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// checkCID rejects values that cannot be a CID before they reach a URL or a
+// file path.
+func checkCID(cid string) error {
+	if cid == "" {
+		return fmt.Errorf("invalid cid %q", cid)
+	}
+	for _, c := range cid {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return fmt.Errorf("invalid cid %q", cid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStorage(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_DIR", t.TempDir())
+	t.Setenv("KUBO_API_URL", "http://127.0.0.1:5001/")
+	t.Setenv("PINNING_SERVICE_URL", "https://pins.example.com/")
+
+	tests := []struct {
+		name    string
+		backend string
+		want    string
+		wantErr bool
+	}{
+		{"Local", "local", "*ipfs.LocalStorage", false},
+		{"Kubo", "Kubo", "*ipfs.KuboStorage", false},
+		{"Pinning service", "pinning_service", "*ipfs.PinningServiceStorage", false},
+		{"Unknown", "s3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := newStorage(tt.backend)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := fmt.Sprintf("%T", storage); got != tt.want {
+				t.Errorf("newStorage() = %s, want %s", got, tt.want)
+			}
+			if kubo, ok := storage.(*KuboStorage); ok && kubo.BaseURL != "http://127.0.0.1:5001" {
+				t.Errorf("Expected trailing slash trimmed, got %s", kubo.BaseURL)
+			}
+		})
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	storage, err := NewLocalStorage(filepath.Join(t.TempDir(), "ipfs"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage() unexpected error: %v", err)
+	}
+
+	content := testContent(600000)
+	res, err := storage.Put(ctx, bytes.NewReader(content), "scan", nil)
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if res.CID != "bafybeidnbbgqbrz3gieyb37t7qkujewoueki3uaevt6pnnwz2oobye7wty" {
+		t.Errorf("Unexpected CID %s", res.CID)
+	}
+	if res.Size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), res.Size)
+	}
+
+	stat, err := storage.Stat(ctx, res.CID)
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if stat.Size != int64(len(content)) {
+		t.Errorf("Expected stat size %d, got %d", len(content), stat.Size)
+	}
+
+	pins, err := storage.ListPins(ctx)
+	if err != nil {
+		t.Fatalf("ListPins() unexpected error: %v", err)
+	}
+	if len(pins) != 1 || !pins[res.CID] {
+		t.Errorf("Expected only %s to be listed, got %v", res.CID, pins)
+	}
+
+	file, err := storage.Get(ctx, res.CID)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	got, _ := io.ReadAll(file)
+	file.Close()
+	if !bytes.Equal(got, content) {
+		t.Error("Get() returned different content")
+	}
+
+	if err := storage.Unpin(ctx, res.CID); err != nil {
+		t.Fatalf("Unpin() unexpected error: %v", err)
+	}
+	if _, err := storage.Stat(ctx, res.CID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after unpin, got %v", err)
+	}
+	if err := storage.Unpin(ctx, res.CID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound unpinning twice, got %v", err)
+	}
+	if _, err := storage.Get(ctx, "../secret"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected invalid cid error, got %v", err)
+	}
+}
+
+func TestLocalStorage_SizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStorage() unexpected error: %v", err)
+	}
+
+	if _, err := storage.Put(context.Background(), bytes.NewReader(make([]byte, MaxFileSize+1)), "big", nil); err == nil {
+		t.Fatal("Expected error for content over the size limit")
+	}
+
+	// The partial upload is not left behind.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no files after failed upload, got %d", len(entries))
+	}
+}
@@ -0,0 +1,51 @@
+package ipfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+var ErrCIDMismatch = errors.New("provider CID does not match the uploaded content")
+
+// VerifiedUpload is content pinned by PutVerified, with the CID the provider
+// returned checked against the one computed locally.
+type VerifiedUpload struct {
+	CID      string
+	Size     int64
+	SHA256   string   // hex encoded
+	Replicas []string // providers that pinned it, set by ReplicatedStorage
+}
+
+// PutVerified pins r through storage while computing its CIDv1 and SHA-256
+// from the bytes the provider reads. A provider CID or size that differs from
+// the local computation is unpinned again and reported as ErrCIDMismatch.
+func PutVerified(ctx context.Context, storage Storage, r io.Reader, name string, meta map[string]string) (*VerifiedUpload, error) {
+	cidWriter := NewCIDWriter()
+	hash := sha256.New()
+
+	res, err := storage.Put(ctx, io.TeeReader(r, io.MultiWriter(cidWriter, hash)), name, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	computed := cidWriter.CID()
+	if res.CID != computed || res.Size != cidWriter.Size() {
+		if err := storage.Unpin(ctx, res.CID); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Error unpinning unverified upload %s: %v", res.CID, err)
+		}
+		return nil, fmt.Errorf("%w: provider returned %s (%d bytes), computed %s (%d bytes)",
+			ErrCIDMismatch, res.CID, res.Size, computed, cidWriter.Size())
+	}
+
+	return &VerifiedUpload{
+		CID:      computed,
+		Size:     cidWriter.Size(),
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+		Replicas: res.Replicas,
+	}, nil
+}
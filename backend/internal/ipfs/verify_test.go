@@ -0,0 +1,79 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fixedCIDStorage reads the upload like a provider would and answers with a
+// fixed CID.
+type fixedCIDStorage struct {
+	cid      string
+	unpinned []string
+}
+
+func (s *fixedCIDStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*PutResult, error) {
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return nil, err
+	}
+	return &PutResult{CID: s.cid, Size: n}, nil
+}
+
+func (s *fixedCIDStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return nil, ErrNotFound
+}
+
+func (s *fixedCIDStorage) Unpin(ctx context.Context, cid string) error {
+	s.unpinned = append(s.unpinned, cid)
+	return nil
+}
+
+func (s *fixedCIDStorage) Stat(ctx context.Context, cid string) (*PinStat, error) {
+	return nil, ErrNotFound
+}
+
+func (s *fixedCIDStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestPutVerified(t *testing.T) {
+	content := testContent(600000)
+	sum := sha256.Sum256(content)
+
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() unexpected error: %v", err)
+	}
+
+	upload, err := PutVerified(context.Background(), storage, bytes.NewReader(content), "scan", nil)
+	if err != nil {
+		t.Fatalf("PutVerified() unexpected error: %v", err)
+	}
+	if upload.CID != "bafybeidnbbgqbrz3gieyb37t7qkujewoueki3uaevt6pnnwz2oobye7wty" {
+		t.Errorf("Unexpected CID %s", upload.CID)
+	}
+	if upload.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected SHA-256 %s", upload.SHA256)
+	}
+	if upload.Size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), upload.Size)
+	}
+}
+
+func TestPutVerified_Mismatch(t *testing.T) {
+	storage := &fixedCIDStorage{cid: "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"}
+
+	_, err := PutVerified(context.Background(), storage, bytes.NewReader([]byte("ciphertext")), "scan", nil)
+	if !errors.Is(err, ErrCIDMismatch) {
+		t.Fatalf("Expected ErrCIDMismatch, got %v", err)
+	}
+	if len(storage.unpinned) != 1 || storage.unpinned[0] != storage.cid {
+		t.Errorf("Expected the unverified pin to be removed, got %v", storage.unpinned)
+	}
+}
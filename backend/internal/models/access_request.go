@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type AccessRequest struct {
+	RecordID          string
+	PatientAddress    string
+	ResearcherAddress string
+	Purpose           string
+	DurationDays      int
+	ExpiresAt         time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type ConsentLog struct {
+	TxHash            string
+	LogIndex          uint
+	BlockNumber       uint64
+	BlockHash         string
+	BlockTimestamp    time.Time
+	EventType         string
+	RecordID          string
+	PatientAddress    string
+	ResearcherAddress string
+	Status            string // Status the consent takes once the log is final
+	Confirmed         bool
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PatientConsent is one consent on a patient's record, joined with the
+// researcher's profile. ResearcherAddress is empty for a record without
+// consents.
+type PatientConsent struct {
+	RecordID          string
+	RecordName        string
+	ResearcherAddress string
+	FullName          string
+	Institution       string
+	Status            string
+	UpdatedAt         time.Time
+}
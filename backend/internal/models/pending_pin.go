@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PendingPin is content an upload worker pinned, or was about to pin, for a
+// record that has not been saved.
+type PendingPin struct {
+	ID        string
+	CID       string
+	RecordID  string
+	Attempts  int // failed unpin attempts
+	CreatedAt time.Time
+}
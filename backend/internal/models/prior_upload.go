@@ -0,0 +1,13 @@
+package models
+
+// PriorUpload is an earlier upload a new one is matched against, by
+// idempotency key or record id, to tell a retry from a conflicting request.
+type PriorUpload struct {
+	JobID             string // empty for records saved before uploads were queued
+	RecordID          string
+	PatientAddress    string
+	DataToEncryptHash string
+	IdempotencyKey    string
+	IPFSCid           string // set when there is no job
+	RecordDeleted     bool   // the record was saved and soft-deleted since
+}
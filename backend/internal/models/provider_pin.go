@@ -0,0 +1,13 @@
+package models
+
+// ProviderPin is a record's content on one storage provider, as tracked in
+// record_pins.
+type ProviderPin struct {
+	RecordID       string
+	Provider       string
+	CID            string
+	Status         string
+	Attempts       int
+	Name           string // of the record, for re-pinning
+	PatientAddress string
+}
@@ -7,9 +7,12 @@ import (
 
 type Record struct {
 	ID                string
+	IDHash            string // keccak256 of ID, as indexed by RecordRegistered
 	IPFSCid           string
 	DataToEncryptHash string
 	AccJson           json.RawMessage
 	Name              string
+	ContentSHA256     string // hex SHA-256 of the uploaded ciphertext
+	ContentSize       int64
 	CreatedAt         time.Time
 }
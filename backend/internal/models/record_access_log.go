@@ -0,0 +1,10 @@
+package models
+
+type RecordAccessLog struct {
+	RecordID        string
+	AccessorAddress string
+	ByteRange       string
+	StatusCode      int
+	BytesServed     int64
+	Gateway         string
+}
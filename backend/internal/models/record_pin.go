@@ -0,0 +1,9 @@
+package models
+
+// RecordPin is a record's content as the pin health check sees it.
+type RecordPin struct {
+	RecordID       string
+	CID            string
+	Name           string
+	PatientAddress string
+}
@@ -0,0 +1,10 @@
+package models
+
+type RecordRegistration struct {
+	RecordIDHash string
+	Owner        string
+	TxHash       string
+	LogIndex     uint
+	BlockNumber  uint64
+	BlockHash    string
+}
@@ -0,0 +1,21 @@
+package models
+
+import "encoding/json"
+
+// UploadJob is a record upload waiting for, or being processed by, an upload
+// worker.
+type UploadJob struct {
+	ID                string
+	RecordID          string
+	PatientAddress    string
+	Name              string
+	DataToEncryptHash string
+	AccJson           json.RawMessage
+	StagingPath       string
+	ContentSize       int64
+	IdempotencyKey    string // optional, from the Idempotency-Key header
+	Status            string
+	Attempts          int
+	IPFSCid           string // set once pinned
+	ContentSHA256     string
+}
@@ -0,0 +1,266 @@
+package pins
+
+import (
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultCheckInterval = time.Hour
+	repinTimeout         = 5 * time.Minute
+)
+
+const (
+	StatusPinned  = "pinned"
+	StatusMissing = "missing"
+)
+
+// source is somewhere the content of a missing pin can be read back from.
+type source struct {
+	name string
+	open func(ctx context.Context, cid string) (io.ReadCloser, error)
+}
+
+// checkResult is the outcome of one pin health check, by CID.
+type checkResult struct {
+	pinned   []string
+	repinned []string
+	missing  []string
+}
+
+// StartMonitor checks every PIN_CHECK_INTERVAL that the storage backend still
+// pins the content of every record, re-pinning missing content from any
+// source that still serves it, and stores the outcome on the records.
+func StartMonitor(ctx context.Context) {
+	interval := getCheckInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runCheck(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runCheck(ctx context.Context) {
+	startedAt := time.Now()
+	result, err := check(ctx)
+	recordCheck(startedAt, result, err)
+	if err != nil {
+		log.Printf("Pin health check failed: %v", err)
+		return
+	}
+
+	for _, status := range []struct {
+		cids     []string
+		status   string
+		repinned bool
+	}{
+		{result.pinned, StatusPinned, false},
+		{result.repinned, StatusPinned, true},
+		{result.missing, StatusMissing, false},
+	} {
+		if err := repositories.SetRecordPinStatus(status.cids, status.status, status.repinned); err != nil {
+			log.Printf("Error storing pin status: %v", err)
+		}
+	}
+
+	log.Printf("Pin health check: %d pinned, %d re-pinned, %d missing",
+		len(result.pinned), len(result.repinned), len(result.missing))
+
+	if storage, err := ipfs.GetStorage(); err == nil {
+		if replicated, ok := storage.(*ipfs.ReplicatedStorage); ok {
+			syncProviderPins(ctx, replicated)
+		}
+	}
+}
+
+// syncProviderPins stores which providers pin each record, after the check so
+// content it re-pinned counts. Content a provider lost goes back to the
+// replicator.
+func syncProviderPins(ctx context.Context, storage *ipfs.ReplicatedStorage) {
+	listedAt := time.Now()
+	byProvider, err := storage.ProviderPins(ctx)
+	if err != nil {
+		log.Printf("Error listing provider pins: %v", err)
+		return
+	}
+
+	for provider, pinned := range byProvider {
+		cids := make([]string, 0, len(pinned))
+		for cid := range pinned {
+			cids = append(cids, cid)
+		}
+		if err := repositories.SyncProviderPins(provider, cids, listedAt); err != nil {
+			log.Printf("Error storing pins of %s: %v", provider, err)
+		}
+	}
+}
+
+func check(ctx context.Context) (checkResult, error) {
+	records, err := repositories.GetRecordPins()
+	if err != nil {
+		return checkResult{}, fmt.Errorf("load records: %w", err)
+	}
+
+	storage, err := ipfs.GetStorage()
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	return checkRecords(ctx, storage, sourcesFor(storage), records)
+}
+
+// checkRecords compares the records against the backend's pins and re-pins
+// what is missing. Records sharing content are checked once.
+func checkRecords(ctx context.Context, storage ipfs.Storage, sources []source, records []models.RecordPin) (checkResult, error) {
+	var result checkResult
+	byCID := make(map[string]models.RecordPin)
+	var cids []string
+	for _, record := range records {
+		if _, seen := byCID[record.CID]; !seen {
+			byCID[record.CID] = record
+			cids = append(cids, record.CID)
+		}
+	}
+
+	pinned, err := listPinned(ctx, storage, cids)
+	if err != nil {
+		return result, fmt.Errorf("list pins: %w", err)
+	}
+
+	for _, cid := range cids {
+		if pinned[cid] {
+			result.pinned = append(result.pinned, cid)
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		record := byCID[cid]
+		if err := repin(ctx, storage, sources, record); err != nil {
+			log.Printf("Content %s of record %s is not pinned and could not be re-pinned: %v", cid, record.RecordID, err)
+			result.missing = append(result.missing, cid)
+			continue
+		}
+		log.Printf("Re-pinned content %s of record %s", cid, record.RecordID)
+		result.repinned = append(result.repinned, cid)
+	}
+
+	return result, nil
+}
+
+// listPinned reports which of the CIDs the backend pins, listing its pins in
+// one go when it supports that.
+func listPinned(ctx context.Context, storage ipfs.Storage, cids []string) (map[string]bool, error) {
+	if lister, ok := storage.(ipfs.PinLister); ok {
+		return lister.ListPins(ctx)
+	}
+
+	pinned := make(map[string]bool, len(cids))
+	for _, cid := range cids {
+		_, err := storage.Stat(ctx, cid)
+		if errors.Is(err, ipfs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pinned[cid] = true
+	}
+	return pinned, nil
+}
+
+// repin reads the content from the first source that serves it and pins it
+// again. The content is verified against the record's CID, so a source
+// serving something else is never pinned in its place.
+func repin(ctx context.Context, storage ipfs.Storage, sources []source, record models.RecordPin) error {
+	ctx, cancel := context.WithTimeout(ctx, repinTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, src := range sources {
+		content, err := src.open(ctx, record.CID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.name, err))
+			continue
+		}
+
+		upload, err := ipfs.PutVerified(ctx, storage, content, record.Name, map[string]string{
+			"patient": record.PatientAddress,
+		})
+		content.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.name, err))
+			continue
+		}
+
+		if upload.CID != record.CID {
+			if err := storage.Unpin(ctx, upload.CID); err != nil && !errors.Is(err, ipfs.ErrNotFound) {
+				log.Printf("Error unpinning %s: %v", upload.CID, err)
+			}
+			errs = append(errs, fmt.Errorf("%s: served content with CID %s", src.name, upload.CID))
+			continue
+		}
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return errors.New("no content source available")
+	}
+	return errors.Join(errs...)
+}
+
+// sourcesFor lists where missing content can be read from: the backend
+// itself, which may still hold unpinned blocks or fetch them from the
+// network, then the configured gateways. Backends that serve content through
+// those same gateways are not asked twice.
+func sourcesFor(storage ipfs.Storage) []source {
+	var sources []source
+	if !ipfs.ServedByGateway(storage) {
+		sources = append(sources, source{name: "storage", open: storage.Get})
+	}
+	return append(sources, gatewaySource())
+}
+
+func gatewaySource() source {
+	gateway := ipfs.GetGateway()
+	return source{
+		name: "gateway",
+		open: func(ctx context.Context, cid string) (io.ReadCloser, error) {
+			resp, err := gateway.Fetch(ctx, cid, "")
+			if err != nil {
+				return nil, err
+			}
+			return resp.Body, nil
+		},
+	}
+}
+
+func getCheckInterval() time.Duration {
+	value := os.Getenv("PIN_CHECK_INTERVAL")
+	if value == "" {
+		return defaultCheckInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid PIN_CHECK_INTERVAL %q, using %s", value, defaultCheckInterval)
+		return defaultCheckInterval
+	}
+	return interval
+}
@@ -0,0 +1,134 @@
+package pins
+
+import (
+	"bytes"
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memStorage pins content in memory under the CID a real backend would give
+// it.
+type memStorage struct {
+	pinned map[string][]byte
+}
+
+func (s *memStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*ipfs.PutResult, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cid := cidOf(content)
+	s.pinned[cid] = content
+	return &ipfs.PutResult{CID: cid, Size: int64(len(content))}, nil
+}
+
+func (s *memStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	content, ok := s.pinned[cid]
+	if !ok {
+		return nil, ipfs.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *memStorage) Unpin(ctx context.Context, cid string) error {
+	if _, ok := s.pinned[cid]; !ok {
+		return ipfs.ErrNotFound
+	}
+	delete(s.pinned, cid)
+	return nil
+}
+
+func (s *memStorage) Stat(ctx context.Context, cid string) (*ipfs.PinStat, error) {
+	content, ok := s.pinned[cid]
+	if !ok {
+		return nil, ipfs.ErrNotFound
+	}
+	return &ipfs.PinStat{CID: cid, Size: int64(len(content))}, nil
+}
+
+func (s *memStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func cidOf(content []byte) string {
+	w := ipfs.NewCIDWriter()
+	w.Write(content)
+	return w.CID()
+}
+
+// mapSource serves content by CID, or the same bytes for every CID when
+// served is set.
+func mapSource(name string, content map[string][]byte, served []byte) source {
+	return source{
+		name: name,
+		open: func(ctx context.Context, cid string) (io.ReadCloser, error) {
+			if served != nil {
+				return io.NopCloser(bytes.NewReader(served)), nil
+			}
+			data, ok := content[cid]
+			if !ok {
+				return nil, ipfs.ErrNotFound
+			}
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+func TestCheckRecords(t *testing.T) {
+	kept := []byte("still pinned")
+	lost := []byte("unpinned but on a gateway")
+	gone := []byte("unpinned everywhere")
+	keptCID, lostCID, goneCID := cidOf(kept), cidOf(lost), cidOf(gone)
+
+	storage := &memStorage{pinned: map[string][]byte{keptCID: kept}}
+	sources := []source{
+		// Serves the wrong bytes, which must not stay pinned.
+		mapSource("stale", nil, []byte("something else")),
+		mapSource("gateway", map[string][]byte{lostCID: lost}, nil),
+	}
+	records := []models.RecordPin{
+		{RecordID: "1", CID: keptCID},
+		{RecordID: "2", CID: lostCID},
+		{RecordID: "3", CID: goneCID},
+		{RecordID: "4", CID: keptCID}, // same content as record 1
+	}
+
+	result, err := checkRecords(context.Background(), storage, sources, records)
+	if err != nil {
+		t.Fatalf("checkRecords() unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result.pinned, []string{keptCID}) {
+		t.Errorf("Expected pinned [%s], got %v", keptCID, result.pinned)
+	}
+	if !slices.Equal(result.repinned, []string{lostCID}) {
+		t.Errorf("Expected repinned [%s], got %v", lostCID, result.repinned)
+	}
+	if !slices.Equal(result.missing, []string{goneCID}) {
+		t.Errorf("Expected missing [%s], got %v", goneCID, result.missing)
+	}
+
+	if !bytes.Equal(storage.pinned[lostCID], lost) {
+		t.Error("Expected the lost content to be pinned again")
+	}
+	if len(storage.pinned) != 2 {
+		t.Errorf("Expected only the records' content to stay pinned, got %d pins", len(storage.pinned))
+	}
+}
+
+func TestRecordCheck(t *testing.T) {
+	defer func() { status = MonitorStatus{} }()
+
+	recordCheck(time.Now(), checkResult{pinned: []string{"a"}, repinned: []string{"b"}, missing: []string{"c"}}, nil)
+	recordCheck(time.Now(), checkResult{repinned: []string{"c"}}, nil)
+
+	got := GetStatus()
+	if got.Checks != 2 || got.Pinned != 1 || got.Missing != 0 || got.Repins != 2 || got.RepinFailures != 1 {
+		t.Errorf("Unexpected status %+v", got)
+	}
+}
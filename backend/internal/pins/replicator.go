@@ -0,0 +1,163 @@
+package pins
+
+import (
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultReplicationInterval = 10 * time.Minute
+	replicationBatchSize       = 100
+)
+
+// StartReplicator keeps every record pinned by every storage provider when
+// STORAGE_PROVIDERS configures more than one. Every REPLICATION_INTERVAL it
+// adds the missing record_pins rows, for records saved before replication or
+// providers added since, then copies the content to the providers that do
+// not pin it yet.
+func StartReplicator(ctx context.Context) {
+	storage, err := ipfs.GetStorage()
+	if err != nil {
+		log.Printf("Pin replicator not started: %v", err)
+		return
+	}
+	replicated, ok := storage.(*ipfs.ReplicatedStorage)
+	if !ok {
+		return
+	}
+
+	interval := getReplicationInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		replicate(ctx, replicated)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func replicate(ctx context.Context, storage *ipfs.ReplicatedStorage) {
+	providers := storage.ProviderNames()
+	added, err := repositories.BackfillRecordPins(providers)
+	if err != nil {
+		log.Printf("Error backfilling record pins: %v", err)
+		return
+	}
+	if added > 0 {
+		log.Printf("Queued %d record replicas", added)
+	}
+
+	// Each row leaves the selection once handled, successfully or not, so
+	// the batches run out.
+	for ctx.Err() == nil {
+		pending, err := repositories.GetUnreplicatedPins(providers, replicationBatchSize)
+		if err != nil {
+			log.Printf("Error loading unreplicated pins: %v", err)
+			return
+		}
+
+		for _, pin := range pending {
+			if ctx.Err() != nil {
+				return
+			}
+			if !storeReplication(ctx, storage, pin) {
+				return
+			}
+		}
+
+		if len(pending) < replicationBatchSize {
+			return
+		}
+	}
+}
+
+// storeReplication replicates the pin and stores the outcome. It returns false
+// when the outcome could not be stored.
+func storeReplication(ctx context.Context, storage *ipfs.ReplicatedStorage, pin models.ProviderPin) bool {
+	target := storage.Provider(pin.Provider)
+	repinned, err := replicateTo(ctx, target, replicaSources(storage, pin.Provider), pin)
+	if ctx.Err() != nil {
+		// Interrupted: leave the row for the next run.
+		return false
+	}
+
+	if err != nil {
+		log.Printf("Error replicating record %s to %s: %v", pin.RecordID, pin.Provider, err)
+		err = repositories.RecordReplicationFailure(pin.RecordID, pin.Provider, err.Error())
+	} else {
+		if repinned {
+			log.Printf("Replicated record %s to %s", pin.RecordID, pin.Provider)
+		}
+		err = repositories.MarkProviderPinned(pin.RecordID, pin.Provider)
+	}
+	if err != nil {
+		log.Printf("Error storing replica state of record %s on %s: %v", pin.RecordID, pin.Provider, err)
+		return false
+	}
+	return true
+}
+
+// replicateTo pins the record's content to target unless it already pins it,
+// reading the content from the sources. It reports whether it pinned.
+func replicateTo(ctx context.Context, target ipfs.Storage, sources []source, pin models.ProviderPin) (bool, error) {
+	if target == nil {
+		return false, errors.New("storage provider is no longer configured")
+	}
+
+	_, err := target.Stat(ctx, pin.CID)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ipfs.ErrNotFound) {
+		return false, err
+	}
+
+	record := models.RecordPin{
+		RecordID:       pin.RecordID,
+		CID:            pin.CID,
+		Name:           pin.Name,
+		PatientAddress: pin.PatientAddress,
+	}
+	if err := repin(ctx, target, sources, record); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// replicaSources lists where content for the target provider can be read
+// from: the other providers, then the gateways.
+func replicaSources(storage *ipfs.ReplicatedStorage, target string) []source {
+	var sources []source
+	for _, provider := range storage.Providers {
+		if provider.Name == target || ipfs.ServedByGateway(provider.Storage) {
+			continue
+		}
+		sources = append(sources, source{name: provider.Name, open: provider.Storage.Get})
+	}
+	return append(sources, gatewaySource())
+}
+
+func getReplicationInterval() time.Duration {
+	value := os.Getenv("REPLICATION_INTERVAL")
+	if value == "" {
+		return defaultReplicationInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid REPLICATION_INTERVAL %q, using %s", value, defaultReplicationInterval)
+		return defaultReplicationInterval
+	}
+	return interval
+}
@@ -0,0 +1,62 @@
+package pins
+
+import (
+	"bytes"
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
+	"context"
+	"testing"
+)
+
+func TestReplicateTo(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("record content")
+	cid := cidOf(content)
+	pin := models.ProviderPin{RecordID: "1", Provider: "target", CID: cid}
+
+	target := &memStorage{pinned: map[string][]byte{}}
+	sources := []source{
+		mapSource("empty", nil, nil),
+		mapSource("holder", map[string][]byte{cid: content}, nil),
+	}
+
+	repinned, err := replicateTo(ctx, target, sources, pin)
+	if err != nil || !repinned {
+		t.Fatalf("replicateTo() = %v, %v, want true, nil", repinned, err)
+	}
+	if !bytes.Equal(target.pinned[cid], content) {
+		t.Error("Expected the content to be pinned to the target")
+	}
+
+	// Already pinned: nothing to copy, even without sources.
+	repinned, err = replicateTo(ctx, target, nil, pin)
+	if err != nil || repinned {
+		t.Errorf("replicateTo() = %v, %v, want false, nil", repinned, err)
+	}
+
+	if _, err := replicateTo(ctx, &memStorage{pinned: map[string][]byte{}}, nil, pin); err == nil {
+		t.Error("Expected an error without any source")
+	}
+	if _, err := replicateTo(ctx, nil, sources, pin); err == nil {
+		t.Error("Expected an error for a provider no longer configured")
+	}
+}
+
+func TestReplicaSources(t *testing.T) {
+	storage, err := ipfs.NewReplicatedStorage([]ipfs.Provider{
+		{Name: "kubo", Storage: &memStorage{}},
+		{Name: "pinata", Storage: &ipfs.PinataStorage{}},
+		{Name: "local", Storage: &memStorage{}},
+	}, 1)
+	if err != nil {
+		t.Fatalf("NewReplicatedStorage() unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, src := range replicaSources(storage, "local") {
+		names = append(names, src.name)
+	}
+	if len(names) != 2 || names[0] != "kubo" || names[1] != "gateway" {
+		t.Errorf("Expected sources [kubo gateway], got %v", names)
+	}
+}
@@ -0,0 +1,51 @@
+package pins
+
+import (
+	"sync"
+	"time"
+)
+
+// MonitorStatus is a snapshot of the pin health checks of this process, for
+// metrics.
+type MonitorStatus struct {
+	Checks        int // completed checks
+	FailedChecks  int
+	LastCheckAt   time.Time
+	LastDuration  time.Duration
+	Pinned        int // CIDs found pinned by the last check
+	Missing       int // CIDs neither pinned nor restored by the last check
+	Repins        int // content restored since startup
+	RepinFailures int // failed attempts to restore content since startup
+	LastError     string
+}
+
+var (
+	statusMu sync.RWMutex
+	status   MonitorStatus
+)
+
+func GetStatus() MonitorStatus {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	return status
+}
+
+func recordCheck(startedAt time.Time, result checkResult, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	status.LastCheckAt = startedAt
+	status.LastDuration = time.Since(startedAt)
+	if err != nil {
+		status.FailedChecks++
+		status.LastError = err.Error()
+		return
+	}
+
+	status.Checks++
+	status.LastError = ""
+	status.Pinned = len(result.pinned) + len(result.repinned)
+	status.Missing = len(result.missing)
+	status.Repins += len(result.repinned)
+	status.RepinFailures += len(result.missing)
+}
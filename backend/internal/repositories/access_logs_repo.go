@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/models"
+	"context"
+	"log"
+)
+
+func SaveRecordAccessLog(entry models.RecordAccessLog) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	var byteRange *string
+	if entry.ByteRange != "" {
+		byteRange = &entry.ByteRange
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`INSERT INTO record_access_logs (record_id, accessor_address, byte_range, status_code, bytes_served, gateway)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.RecordID, entry.AccessorAddress, byteRange, entry.StatusCode, entry.BytesServed, entry.Gateway)
+	if err != nil {
+		log.Println("Error saving record access log:", err)
+		return err
+	}
+	return nil
+}
+
+// GetRecordAccessLogs returns the downloads of a record, newest first.
+func GetRecordAccessLogs(recordID string) ([]dtos.RecordAccessLogResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT accessor_address, byte_range, status_code, bytes_served, accessed_at
+		FROM record_access_logs
+		WHERE record_id = $1
+		ORDER BY accessed_at DESC`, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []dtos.RecordAccessLogResponse{}
+	for rows.Next() {
+		var entry dtos.RecordAccessLogResponse
+		if err := rows.Scan(
+			&entry.AccessorAddress,
+			&entry.ByteRange,
+			&entry.StatusCode,
+			&entry.BytesServed,
+			&entry.AccessedAt,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, entry)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,292 @@
+package repositories
+
+import (
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrOpenAccessRequest       = errors.New("an open access request already exists for this record")
+	ErrAccessRequestNotPending = errors.New("access request is no longer pending")
+)
+
+const accessRequestColumns = `
+	a.id, a.record_id, r.name, a.patient_address, a.researcher_address, a.purpose, a.duration_days,
+	a.status, a.decline_reason, a.fulfilled_tx_hash, a.expires_at, a.responded_at, a.created_at, a.updated_at`
+
+// AccessRequestLimitError is returned by CreateAccessRequest when the
+// researcher already sent the patient the allowed number of requests in the
+// window.
+// Oldest is when the oldest of them was sent.
+type AccessRequestLimitError struct {
+	Oldest time.Time
+}
+
+func (e *AccessRequestLimitError) Error() string {
+	return "too many access requests"
+}
+
+// CreateAccessRequest stores a pending request unless the researcher sent the
+// patient limit requests since the given time. ErrOpenAccessRequest is
+// returned when the researcher already has a pending or approved request for
+// the record. The count and the insert run under a transaction lock on the
+// researcher and patient pair so concurrent requests cannot both pass.
+func CreateAccessRequest(request models.AccessRequest, limit int, since time.Time) (*dtos.AccessRequestResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := expireAccessRequests(ctx, pool); err != nil {
+		return nil, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`SELECT pg_advisory_xact_lock(hashtext('access_requests:' || LOWER($1) || ':' || LOWER($2)))`,
+		request.ResearcherAddress, request.PatientAddress); err != nil {
+		log.Println("Error locking access requests:", err)
+		return nil, err
+	}
+
+	var count int
+	var oldest *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*), MIN(created_at)
+		FROM access_requests
+		WHERE LOWER(researcher_address) = LOWER($1)
+			AND LOWER(patient_address) = LOWER($2)
+			AND created_at >= $3`,
+		request.ResearcherAddress, request.PatientAddress, since).Scan(&count, &oldest)
+	if err != nil {
+		log.Println("Error counting access requests:", err)
+		return nil, err
+	}
+	if count >= limit && oldest != nil {
+		return nil, &AccessRequestLimitError{Oldest: *oldest}
+	}
+
+	var id string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO access_requests (record_id, patient_address, researcher_address, purpose, duration_days, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		request.RecordID, request.PatientAddress, request.ResearcherAddress, request.Purpose,
+		request.DurationDays, request.ExpiresAt).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrOpenAccessRequest
+		}
+		log.Println("Error creating access request:", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return GetAccessRequestByID(id)
+}
+
+func GetAccessRequestByID(id string) (*dtos.AccessRequestResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := expireAccessRequests(ctx, pool); err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT`+accessRequestColumns+`
+		FROM access_requests a
+		INNER JOIN records r ON r.id = a.record_id
+		WHERE a.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := scanAccessRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	return &requests[0], nil
+}
+
+// GetAccessRequestsByPatient lists the requests sent to a patient, newest
+// first, optionally filtered by status.
+func GetAccessRequestsByPatient(patientAddress string, status string) ([]dtos.AccessRequestResponse, error) {
+	return getAccessRequestsBy("a.patient_address", patientAddress, status)
+}
+
+// GetAccessRequestsByResearcher lists the requests a researcher sent, newest
+// first, optionally filtered by status.
+func GetAccessRequestsByResearcher(researcherAddress string, status string) ([]dtos.AccessRequestResponse, error) {
+	return getAccessRequestsBy("a.researcher_address", researcherAddress, status)
+}
+
+func getAccessRequestsBy(column string, address string, status string) ([]dtos.AccessRequestResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := expireAccessRequests(ctx, pool); err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT`+accessRequestColumns+`
+		FROM access_requests a
+		INNER JOIN records r ON r.id = a.record_id
+		WHERE LOWER(`+column+`) = LOWER($1) AND ($2::text = '' OR a.status = $2::text)
+		ORDER BY a.created_at DESC`, address, status)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAccessRequests(rows)
+}
+
+// RespondToAccessRequest moves a pending request to approved or declined.
+// ErrAccessRequestNotPending is returned when it was already answered or has
+// expired.
+func RespondToAccessRequest(id string, status string, declineReason string) (*dtos.AccessRequestResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := expireAccessRequests(ctx, pool); err != nil {
+		return nil, err
+	}
+
+	var reason *string
+	if declineReason != "" {
+		reason = &declineReason
+	}
+
+	result, err := pool.Exec(ctx,
+		`UPDATE access_requests
+		SET status = $2, decline_reason = $3, responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'`, id, status, reason)
+	if err != nil {
+		log.Println("Error updating access request:", err)
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrAccessRequestNotPending
+	}
+
+	return GetAccessRequestByID(id)
+}
+
+// FulfillAccessRequests marks the open requests of a researcher for a record
+// fulfilled by a ConsentGranted log. Requests sent after the block was mined
+// are left open, so replayed history cannot fulfil them.
+func FulfillAccessRequests(recordID string, researcherAddress string, txHash string, grantedAt time.Time) (int64, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	result, err := pool.Exec(ctx,
+		`UPDATE access_requests
+		SET status = 'fulfilled', fulfilled_tx_hash = $3
+		WHERE record_id = $1
+			AND LOWER(researcher_address) = LOWER($2)
+			AND status IN ('pending', 'approved')
+			AND created_at <= $4`,
+		recordID, researcherAddress, txHash, grantedAt)
+	if err != nil {
+		log.Println("Error fulfilling access requests:", err)
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ReopenAccessRequests undoes FulfillAccessRequests for a grant that was
+// reorged out.
+func ReopenAccessRequests(txHash string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE access_requests
+		SET status = CASE WHEN responded_at IS NULL THEN 'pending' ELSE 'approved' END,
+			fulfilled_tx_hash = NULL
+		WHERE fulfilled_tx_hash = $1 AND status = 'fulfilled'`, txHash)
+	if err != nil {
+		log.Println("Error reopening access requests:", err)
+		return err
+	}
+	return nil
+}
+
+// expireAccessRequests closes pending requests past their expiry, so they no
+// longer block a new request.
+func expireAccessRequests(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx,
+		`UPDATE access_requests SET status = 'expired'
+		WHERE status = 'pending' AND expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		log.Println("Error expiring access requests:", err)
+	}
+	return err
+}
+
+func scanAccessRequests(rows pgx.Rows) ([]dtos.AccessRequestResponse, error) {
+	defer rows.Close()
+
+	requests := []dtos.AccessRequestResponse{}
+	for rows.Next() {
+		var request dtos.AccessRequestResponse
+		if err := rows.Scan(
+			&request.ID,
+			&request.RecordID,
+			&request.RecordName,
+			&request.PatientAddress,
+			&request.ResearcherAddress,
+			&request.Purpose,
+			&request.DurationDays,
+			&request.Status,
+			&request.DeclineReason,
+			&request.FulfilledTxHash,
+			&request.ExpiresAt,
+			&request.RespondedAt,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
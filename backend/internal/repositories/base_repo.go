@@ -6,68 +6,133 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	dbPool   *pgxpool.Pool
-	poolOnce sync.Once
-	poolErr  error
+	dbPool *pgxpool.Pool
+	poolMu sync.Mutex
+
+	// poolDialing is closed when the connection attempt in flight ends.
+	poolDialing chan struct{}
+	poolErr     error
+	poolRetryAt time.Time
+
+	// poolRetryDelay spaces connection attempts after a failure, so callers
+	// hitting a database that is down do not each wait on a new attempt.
+	poolRetryDelay = time.Second
 )
 
+// GetDB returns the shared connection pool, creating it on first use. One
+// caller connects while the others wait for it, without holding poolMu. A
+// failed attempt is not kept: its error is returned until poolRetryDelay has
+// passed, then the next call tries to connect again.
 func GetDB() (*pgxpool.Pool, error) {
-	poolOnce.Do(func() {
-		connString := os.Getenv("DATABASE_CONNECTION_STRING")
-		if connString == "" {
-			poolErr = errors.New("DATABASE_CONNECTION_STRING environment variable not set")
-			return
+	for {
+		poolMu.Lock()
+		if dbPool != nil {
+			poolMu.Unlock()
+			return dbPool, nil
 		}
-
-		config, err := pgxpool.ParseConfig(connString)
-		if err != nil {
-			poolErr = fmt.Errorf("failed to parse connection string: %w", err)
-			return
+		if time.Now().Before(poolRetryAt) {
+			err := poolErr
+			poolMu.Unlock()
+			return nil, err
+		}
+		if dialing := poolDialing; dialing != nil {
+			poolMu.Unlock()
+			<-dialing
+			continue
 		}
+		dialing := make(chan struct{})
+		poolDialing = dialing
+		poolMu.Unlock()
 
-		// Configure connection pool
-		config.MaxConns = 25
-		config.MinConns = 5
-		config.MaxConnLifetime = 5 * time.Minute
-		config.MaxConnIdleTime = 1 * time.Minute
-		config.HealthCheckPeriod = 30 * time.Second
+		pool, err := newPool()
 
-		dbPool, err = pgxpool.NewWithConfig(context.Background(), config)
+		poolMu.Lock()
+		poolDialing = nil
+		close(dialing)
 		if err != nil {
-			poolErr = fmt.Errorf("failed to create connection pool: %w", err)
-			return
+			poolErr = err
+			poolRetryAt = time.Now().Add(poolRetryDelay)
+			poolMu.Unlock()
+			return nil, err
 		}
+		dbPool = pool
+		poolMu.Unlock()
 
-		// Verify connection
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		log.Println("Database connection pool initialized successfully")
+		return pool, nil
+	}
+}
 
-		if err := dbPool.Ping(ctx); err != nil {
-			dbPool.Close()
-			poolErr = fmt.Errorf("failed to ping database: %w", err)
-			return
-		}
+func newPool() (*pgxpool.Pool, error) {
+	connString := os.Getenv("DATABASE_CONNECTION_STRING")
+	if connString == "" {
+		return nil, errors.New("DATABASE_CONNECTION_STRING environment variable not set")
+	}
 
-		log.Println("Database connection pool initialized successfully")
-	})
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
 
-	if poolErr != nil {
-		return nil, poolErr
+	// Configure connection pool
+	config.MaxConns = 25
+	config.MinConns = 5
+	config.MaxConnLifetime = 5 * time.Minute
+	config.MaxConnIdleTime = 1 * time.Minute
+	config.HealthCheckPeriod = 30 * time.Second
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	// Verify connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return dbPool, nil
+	return pool, nil
 }
 
 func CloseDB() {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
 	if dbPool != nil {
 		dbPool.Close()
+		dbPool = nil
 		log.Println("Database connection pool closed")
 	}
 }
+
+func PingDB(ctx context.Context) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+	return pool.Ping(ctx)
+}
+
+// IsPermanentError reports whether retrying the statement can never succeed,
+// such as a constraint violation or malformed input.
+func IsPermanentError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// Class 22: data exception, class 23: integrity constraint violation
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// A failed connection attempt must not stick: /ready would otherwise fail
+// forever when Postgres was down at startup.
+func TestGetDBRetriesAfterFailure(t *testing.T) {
+	defer func(delay time.Duration) { poolRetryDelay = delay }(poolRetryDelay)
+	poolRetryDelay = 0
+
+	t.Setenv("DATABASE_CONNECTION_STRING", "")
+	if _, err := GetDB(); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Fatalf("GetDB() error = %v, want missing connection string", err)
+	}
+
+	t.Setenv("DATABASE_CONNECTION_STRING", "postgres://%zz")
+	if _, err := GetDB(); err == nil || !strings.Contains(err.Error(), "failed to parse connection string") {
+		t.Fatalf("GetDB() error = %v, want parse error from a new attempt", err)
+	}
+}
+
+func TestGetDBBacksOffAfterFailure(t *testing.T) {
+	defer func(delay time.Duration) { poolRetryDelay = delay }(poolRetryDelay)
+	poolRetryDelay = time.Hour
+	defer func() { poolRetryAt = time.Time{} }()
+
+	t.Setenv("DATABASE_CONNECTION_STRING", "")
+	if _, err := GetDB(); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Fatalf("GetDB() error = %v, want missing connection string", err)
+	}
+
+	t.Setenv("DATABASE_CONNECTION_STRING", "postgres://%zz")
+	if _, err := GetDB(); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Fatalf("GetDB() error = %v, want the earlier error within the retry delay", err)
+	}
+}
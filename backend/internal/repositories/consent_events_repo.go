@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"consentis-api/internal/dtos"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const consentEventColumns = `
+	e.record_id, e.patient_address, e.researcher_address, e.event_type,
+	e.tx_hash, e.log_index, e.block_number, e.block_timestamp,
+	COALESCE(l.confirmed, FALSE), e.removed`
+
+const consentEventJoin = `
+	FROM consent_events e
+	LEFT JOIN consent_logs l
+		ON l.tx_hash = e.tx_hash AND l.log_index = e.log_index AND l.block_hash = e.block_hash`
+
+// GetConsentHistoryByRecordID returns the consent events of a record in chain
+// order. A non-empty researcherAddress limits the history to that researcher.
+func GetConsentHistoryByRecordID(recordID string, researcherAddress string, includeRemoved bool) ([]dtos.ConsentEventResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT`+consentEventColumns+consentEventJoin+`
+		WHERE e.record_id = $1
+			AND ($2 = '' OR LOWER(e.researcher_address) = LOWER($2))
+			AND ($3 OR NOT e.removed)
+		ORDER BY e.block_number, e.log_index, e.created_at`,
+		recordID, researcherAddress, includeRemoved)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanConsentEvents(rows)
+}
+
+// GetConsentHistoryByAddress returns, in chain order, the consent events where
+// the address is either the patient or the researcher.
+func GetConsentHistoryByAddress(address string, includeRemoved bool) ([]dtos.ConsentEventResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT`+consentEventColumns+consentEventJoin+`
+		WHERE (LOWER(e.patient_address) = LOWER($1) OR LOWER(e.researcher_address) = LOWER($1))
+			AND ($2 OR NOT e.removed)
+		ORDER BY e.block_number, e.log_index, e.created_at`,
+		address, includeRemoved)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanConsentEvents(rows)
+}
+
+func scanConsentEvents(rows pgx.Rows) ([]dtos.ConsentEventResponse, error) {
+	defer rows.Close()
+
+	events := []dtos.ConsentEventResponse{}
+	for rows.Next() {
+		var event dtos.ConsentEventResponse
+		var logIndex, blockNumber int64
+		if err := rows.Scan(
+			&event.RecordID,
+			&event.PatientAddress,
+			&event.ResearcherAddress,
+			&event.EventType,
+			&event.TxHash,
+			&logIndex,
+			&blockNumber,
+			&event.BlockTimestamp,
+			&event.Confirmed,
+			&event.Removed,
+		); err != nil {
+			return nil, err
+		}
+		event.LogIndex = uint(logIndex)
+		event.BlockNumber = uint64(blockNumber)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -1,34 +1,380 @@
 package repositories
 
 import (
+	"consentis-api/internal/dtos"
 	"consentis-api/internal/models"
 	"context"
+	"errors"
 	"log"
+
+	"github.com/jackc/pgx/v5"
 )
 
-func SaveConsent(consent models.Consent, txHash string) error {
+// ApplyConsentLog appends a consent log to the event history and applies it
+// to the consents table. (tx hash, log index) is the idempotency key: a log that was already applied
+// is not applied again and false is returned, as is a log older than the one
+// that last changed the consent. Unconfirmed logs leave the consent in
+// "pending" until ConfirmConsentLog is called. The consent state the log
+// replaced is kept so RollbackConsentLog can restore it after a reorg.
+func ApplyConsentLog(entry models.ConsentLog) (bool, error) {
 	pool, err := GetDB()
 	if err != nil {
-		return err
+		return false, err
 	}
-
 	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	applied, err := applyConsentLog(ctx, tx, entry)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Println(err)
+		return false, err
+	}
 
-	_, err = pool.Exec(ctx,
-		`INSERT INTO consents (record_id, researcher_address, status, last_tx_hash) 
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (record_id, researcher_address) 
-		DO UPDATE SET 
+	if applied {
+		log.Println("Row inserted/updated successfully into consents.")
+	}
+	return applied, nil
+}
+
+func applyConsentLog(ctx context.Context, tx pgx.Tx, entry models.ConsentLog) (bool, error) {
+	// Serialise on the consent row before checking the idempotency key, so
+	// two writers of the same consent cannot interleave.
+	var previousStatus, previousTxHash *string
+	var previousBlock, previousIndex *int64
+	err := tx.QueryRow(ctx,
+		`SELECT status, last_tx_hash, last_block_number, last_log_index FROM consents
+		WHERE record_id = $1 AND researcher_address = $2
+		FOR UPDATE`, entry.RecordID, entry.ResearcherAddress).Scan(&previousStatus, &previousTxHash, &previousBlock, &previousIndex)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	if err := appendConsentEvent(ctx, tx, entry); err != nil {
+		return false, err
+	}
+
+	// A replayed log can only have moved block, record where it lives now
+	// without touching the consent.
+	result, err := tx.Exec(ctx,
+		`UPDATE consent_logs
+		SET block_number = $3, block_hash = $4, confirmed = confirmed OR $5
+		WHERE tx_hash = $1 AND log_index = $2`,
+		entry.TxHash, int64(entry.LogIndex), int64(entry.BlockNumber), entry.BlockHash, entry.Confirmed)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() > 0 {
+		return false, nil
+	}
+
+	if previousBlock != nil && previousIndex != nil && !isNewerPosition(entry, *previousBlock, *previousIndex) {
+		log.Printf("Ignoring consent log tx=%s index=%d older than the current state", entry.TxHash, entry.LogIndex)
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO consent_logs
+			(tx_hash, log_index, block_number, block_hash, record_id, researcher_address,
+			status, previous_status, previous_tx_hash, previous_block_number, previous_log_index, confirmed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		entry.TxHash, int64(entry.LogIndex), int64(entry.BlockNumber), entry.BlockHash, entry.RecordID,
+		entry.ResearcherAddress, entry.Status, previousStatus, previousTxHash, previousBlock, previousIndex, entry.Confirmed)
+	if err != nil {
+		return false, err
+	}
+
+	status := entry.Status
+	if !entry.Confirmed {
+		status = "pending"
+	}
+
+	// Consents of a deleted record stay revoked.
+	_, err = tx.Exec(ctx,
+		`INSERT INTO consents (record_id, researcher_address, status, last_tx_hash, last_block_number, last_log_index)
+		SELECT $1::uuid, $2::varchar, $3::varchar, $4::varchar, $5::bigint, $6::integer
+		WHERE NOT EXISTS (SELECT 1 FROM records WHERE id = $1 AND deleted_at IS NOT NULL)
+		ON CONFLICT (record_id, researcher_address)
+		DO UPDATE SET
 			status = EXCLUDED.status,
 			last_tx_hash = EXCLUDED.last_tx_hash,
+			last_block_number = EXCLUDED.last_block_number,
+			last_log_index = EXCLUDED.last_log_index,
 			updated_at = CURRENT_TIMESTAMP;`,
-		consent.RecordID, consent.ResearcherAddress, consent.Status, txHash)
+		entry.RecordID, entry.ResearcherAddress, status, entry.TxHash, int64(entry.BlockNumber), int64(entry.LogIndex))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// appendConsentEvent adds the log to consent_events. The history is keyed by
+// block hash as well, so a log re-included in another block gets its own row
+// and the copies left in orphaned blocks are flagged as removed.
+func appendConsentEvent(ctx context.Context, tx pgx.Tx, entry models.ConsentLog) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO consent_events
+			(record_id, patient_address, researcher_address, event_type,
+			tx_hash, log_index, block_number, block_hash, block_timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (tx_hash, log_index, block_hash)
+		DO UPDATE SET removed = FALSE
+		WHERE consent_events.removed`,
+		entry.RecordID, entry.PatientAddress, entry.ResearcherAddress, entry.EventType,
+		entry.TxHash, int64(entry.LogIndex), int64(entry.BlockNumber), entry.BlockHash, entry.BlockTimestamp)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE consent_events SET removed = TRUE
+		WHERE tx_hash = $1 AND log_index = $2 AND block_hash <> $3 AND NOT removed`,
+		entry.TxHash, int64(entry.LogIndex), entry.BlockHash)
+	return err
+}
+
+func isNewerPosition(entry models.ConsentLog, blockNumber int64, logIndex int64) bool {
+	if int64(entry.BlockNumber) != blockNumber {
+		return int64(entry.BlockNumber) > blockNumber
+	}
+	return int64(entry.LogIndex) > logIndex
+}
+
+// ConfirmConsentLog marks a log final and, if it is still the latest change
+// to its consent, moves the consent from "pending" to the log's status.
+func ConfirmConsentLog(txHash string, logIndex uint) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
+	var recordID, researcherAddress, status string
+	err = tx.QueryRow(ctx,
+		`UPDATE consent_logs SET confirmed = TRUE
+		WHERE tx_hash = $1 AND log_index = $2
+		RETURNING record_id, researcher_address, status`,
+		txHash, int64(logIndex)).Scan(&recordID, &researcherAddress, &status)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
 		log.Println(err)
 		return err
 	}
 
-	log.Println("Row inserted/updated successfully into consents.")
+	// Consents of a deleted record stay revoked.
+	_, err = tx.Exec(ctx,
+		`UPDATE consents SET status = $3
+		WHERE record_id = $1 AND researcher_address = $2 AND last_tx_hash = $4
+			AND NOT EXISTS (SELECT 1 FROM records WHERE id = $1 AND deleted_at IS NOT NULL)`,
+		recordID, researcherAddress, status, txHash)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RollbackConsentLog undoes a log that is no longer on the canonical chain,
+// restoring the consent to the state it had before the log was applied. The
+// log stays in the event history, flagged as removed.
+func RollbackConsentLog(txHash string, logIndex uint) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE consent_events SET removed = TRUE
+		WHERE tx_hash = $1 AND log_index = $2 AND NOT removed`,
+		txHash, int64(logIndex))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	var recordID, researcherAddress string
+	var previousStatus, previousTxHash *string
+	var previousBlock, previousIndex *int64
+	err = tx.QueryRow(ctx,
+		`DELETE FROM consent_logs
+		WHERE tx_hash = $1 AND log_index = $2
+		RETURNING record_id, researcher_address, previous_status, previous_tx_hash, previous_block_number, previous_log_index`,
+		txHash, int64(logIndex)).Scan(&recordID, &researcherAddress, &previousStatus, &previousTxHash, &previousBlock, &previousIndex)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tx.Commit(ctx)
+		}
+		log.Println(err)
+		return err
+	}
+
+	if previousStatus == nil {
+		_, err = tx.Exec(ctx,
+			`DELETE FROM consents
+			WHERE record_id = $1 AND researcher_address = $2 AND last_tx_hash = $3`,
+			recordID, researcherAddress, txHash)
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE consents
+			SET status = $3, last_tx_hash = $4, last_block_number = $5, last_log_index = $6
+			WHERE record_id = $1 AND researcher_address = $2 AND last_tx_hash = $7`,
+			recordID, researcherAddress, *previousStatus, previousTxHash, previousBlock, previousIndex, txHash)
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	log.Printf("Rolled back consent log tx=%s index=%d", txHash, logIndex)
 	return nil
 }
+
+// GetUnconfirmedConsentLogs returns the logs still waiting for enough
+// confirmations, oldest first.
+func GetUnconfirmedConsentLogs() ([]models.ConsentLog, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT tx_hash, log_index, block_number, block_hash, record_id, researcher_address, status
+		FROM consent_logs
+		WHERE NOT confirmed
+		ORDER BY block_number, log_index`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.ConsentLog
+	for rows.Next() {
+		var entry models.ConsentLog
+		var logIndex, blockNumber int64
+		if err := rows.Scan(
+			&entry.TxHash,
+			&logIndex,
+			&blockNumber,
+			&entry.BlockHash,
+			&entry.RecordID,
+			&entry.ResearcherAddress,
+			&entry.Status,
+		); err != nil {
+			return nil, err
+		}
+		entry.LogIndex = uint(logIndex)
+		entry.BlockNumber = uint64(blockNumber)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func GetConsentsByRecordID(recordID string) ([]dtos.RecordConsentResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT researcher_address, status, COALESCE(last_tx_hash, ''), updated_at
+		FROM consents
+		WHERE record_id = $1
+		ORDER BY updated_at DESC`, recordID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []dtos.RecordConsentResponse{}
+	for rows.Next() {
+		var consent dtos.RecordConsentResponse
+		if err := rows.Scan(
+			&consent.ResearcherAddress,
+			&consent.Status,
+			&consent.LastTxHash,
+			&consent.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, nil
+}
+
+// GetPatientConsents returns every consent on a patient's active records with
+// the researcher's profile, and one row without a researcher for records that
+// have no consents.
+func GetPatientConsents(patientAddress string) ([]models.PatientConsent, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT r.id, r.name,
+			COALESCE(c.researcher_address, ''),
+			COALESCE(rp.full_name, ''),
+			COALESCE(rp.institution, ''),
+			COALESCE(c.status, ''),
+			COALESCE(c.updated_at, r.created_at)
+		FROM records r
+		INNER JOIN users u ON r.patient_id = u.id
+		LEFT JOIN consents c ON c.record_id = r.id
+		LEFT JOIN users ru ON LOWER(ru.wallet_address) = LOWER(c.researcher_address)
+		LEFT JOIN researcher_profiles rp ON rp.user_id = ru.id
+		WHERE u.wallet_address = $1 AND r.deleted_at IS NULL
+		ORDER BY r.created_at DESC, c.updated_at DESC`, patientAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consents []models.PatientConsent
+	for rows.Next() {
+		var consent models.PatientConsent
+		if err := rows.Scan(
+			&consent.RecordID,
+			&consent.RecordName,
+			&consent.ResearcherAddress,
+			&consent.FullName,
+			&consent.Institution,
+			&consent.Status,
+			&consent.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
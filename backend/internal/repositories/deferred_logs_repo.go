@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"cmp"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	deferredRegistration = "RecordRegistered"
+	deferredConsent      = "Consent"
+)
+
+// DeferRecordRegistration keeps a registration that matched no record for
+// when the record is inserted, provided an upload of it is still open. It
+// returns false when there is none.
+func DeferRecordRegistration(reg models.RecordRegistration) (bool, error) {
+	payload, err := json.Marshal(reg)
+	if err != nil {
+		return false, err
+	}
+	return deferChainLog(
+		`SELECT id FROM upload_jobs
+		WHERE record_id_hash = $6 AND status NOT IN ('completed', 'failed')
+		LIMIT 1
+		FOR UPDATE`,
+		reg.TxHash, reg.LogIndex, reg.BlockNumber, deferredRegistration, payload, reg.RecordIDHash)
+}
+
+// DeferConsentLog keeps a consent log about a record that does not exist yet
+// for when it is inserted, provided an upload of it is still open. It returns
+// false when there is none.
+func DeferConsentLog(entry models.ConsentLog) (bool, error) {
+	if !helpers.IsValidUUID(entry.RecordID) {
+		return false, nil
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+	return deferChainLog(
+		`SELECT id FROM upload_jobs
+		WHERE record_id = $6::uuid AND status NOT IN ('completed', 'failed')
+		LIMIT 1
+		FOR UPDATE`,
+		entry.TxHash, entry.LogIndex, entry.BlockNumber, deferredConsent, payload, entry.RecordID)
+}
+
+// deferChainLog stores the log when openJob finds an upload. The job row is
+// locked, so the log is either stored before the upload completes, which
+// then applies it, or not at all.
+func deferChainLog(openJob string, txHash string, logIndex uint, blockNumber uint64, eventType string, payload []byte, key string) (bool, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return false, err
+	}
+
+	keyColumn, keyValue := "record_id", "$6::uuid"
+	if eventType == deferredRegistration {
+		keyColumn, keyValue = "record_id_hash", "$6"
+	}
+
+	ctx := context.Background()
+	tag, err := pool.Exec(ctx,
+		`WITH job AS (`+openJob+`)
+		INSERT INTO deferred_chain_logs (tx_hash, log_index, block_number, event_type, payload, `+keyColumn+`)
+		SELECT $1, $2, $3, $4, $5, `+keyValue+` FROM job
+		ON CONFLICT (tx_hash, log_index)
+		DO UPDATE SET block_number = EXCLUDED.block_number, payload = EXCLUDED.payload`,
+		txHash, int64(logIndex), int64(blockNumber), eventType, payload, key)
+	if err != nil {
+		log.Println("Error deferring chain log:", err)
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DiscardDeferredLog drops a deferred log that was reorged out.
+func DiscardDeferredLog(txHash string, logIndex uint) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`DELETE FROM deferred_chain_logs WHERE tx_hash = $1 AND log_index = $2`, txHash, int64(logIndex))
+	return err
+}
+
+// applyDeferredLogs applies, in chain order, the logs that arrived before the
+// record was inserted, in the transaction that inserts it.
+func applyDeferredLogs(ctx context.Context, tx pgx.Tx, recordID string, recordIDHash string) error {
+	rows, err := tx.Query(ctx,
+		`DELETE FROM deferred_chain_logs
+		WHERE record_id = $1 OR record_id_hash = $2
+		RETURNING event_type, payload, block_number, log_index`, recordID, recordIDHash)
+	if err != nil {
+		return err
+	}
+
+	type deferredLog struct {
+		eventType   string
+		payload     []byte
+		blockNumber int64
+		logIndex    int64
+	}
+	var logs []deferredLog
+	for rows.Next() {
+		var l deferredLog
+		if err := rows.Scan(&l.eventType, &l.payload, &l.blockNumber, &l.logIndex); err != nil {
+			rows.Close()
+			return err
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// DELETE ... RETURNING has no order.
+	slices.SortFunc(logs, func(a, b deferredLog) int {
+		if a.blockNumber != b.blockNumber {
+			return cmp.Compare(a.blockNumber, b.blockNumber)
+		}
+		return cmp.Compare(a.logIndex, b.logIndex)
+	})
+
+	for _, l := range logs {
+		if err := applyDeferredLog(ctx, tx, l.eventType, l.payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDeferredLog applies one log in a savepoint. Like the indexer, it skips
+// a log the database permanently rejects rather than fail the upload.
+func applyDeferredLog(ctx context.Context, tx pgx.Tx, eventType string, payload []byte) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer savepoint.Rollback(ctx)
+
+	var description string
+	switch eventType {
+	case deferredRegistration:
+		var reg models.RecordRegistration
+		if err := json.Unmarshal(payload, &reg); err != nil {
+			return err
+		}
+		description = fmt.Sprintf("registration tx=%s", reg.TxHash)
+		_, _, _, err = applyRecordRegistration(ctx, savepoint, reg)
+	case deferredConsent:
+		var entry models.ConsentLog
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			return err
+		}
+		description = fmt.Sprintf("consent log tx=%s index=%d", entry.TxHash, entry.LogIndex)
+		_, err = applyConsentLog(ctx, savepoint, entry)
+	default:
+		return errors.New("unknown deferred log type " + eventType)
+	}
+
+	if IsPermanentError(err) {
+		log.Printf("Skipping deferred %s: %v", description, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("apply deferred %s: %w", description, err)
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("Applied deferred %s", description)
+	return nil
+}
+
+// IsMissingRecordError reports whether the statement failed because the
+// record it references does not exist.
+func IsMissingRecordError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
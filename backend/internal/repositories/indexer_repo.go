@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetIndexerCursor returns the last processed block for a listener stream and
+// whether a cursor has been stored yet.
+func GetIndexerCursor(name string) (uint64, bool, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return 0, false, err
+	}
+
+	ctx := context.Background()
+	var lastBlock int64
+	err = pool.QueryRow(ctx,
+		`SELECT last_block FROM indexer_cursors WHERE name = $1`, name).Scan(&lastBlock)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		log.Println("Error fetching indexer cursor:", err)
+		return 0, false, err
+	}
+
+	return uint64(lastBlock), true, nil
+}
+
+func SaveIndexerCursor(name string, lastBlock uint64) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`INSERT INTO indexer_cursors (name, last_block)
+		VALUES ($1, $2)
+		ON CONFLICT (name)
+		DO UPDATE SET
+			last_block = EXCLUDED.last_block,
+			updated_at = CURRENT_TIMESTAMP`, name, int64(lastBlock))
+
+	if err != nil {
+		log.Println("Error saving indexer cursor:", err)
+		return err
+	}
+
+	return nil
+}
+
+// RewindIndexerCursors moves every cursor whose name starts with prefix back
+// to lastBlock, leaving cursors that are already behind it untouched.
+func RewindIndexerCursors(prefix string, lastBlock uint64) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE indexer_cursors
+		SET last_block = LEAST(last_block, $2), updated_at = CURRENT_TIMESTAMP
+		WHERE name LIKE $1 || '%'`, prefix, int64(lastBlock))
+
+	if err != nil {
+		log.Println("Error rewinding indexer cursors:", err)
+		return err
+	}
+
+	return nil
+}
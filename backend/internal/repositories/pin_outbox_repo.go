@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"consentis-api/internal/models"
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// orphanPinFilter matches pending pins older than the grace period ($1, in
+// seconds) whose upload job can no longer save a record.
+const orphanPinFilter = `
+	o.status = 'pending'
+	AND o.created_at < NOW() - $1 * INTERVAL '1 second'
+	AND NOT EXISTS (
+		SELECT 1 FROM upload_jobs j
+		WHERE j.id = o.upload_job_id AND j.status IN ('queued', 'pinning', 'saving')
+	)`
+
+// RecordPendingPin notes that the job is about to pin cid, before the upload
+// starts, so the content can be unpinned if the record is never saved.
+func RecordPendingPin(uploadJobID string, recordID string, cid string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`INSERT INTO pin_outbox (upload_job_id, record_id, cid)
+		VALUES ($1, $2, $3)`, uploadJobID, recordID, cid)
+	if err != nil {
+		log.Println("Error recording pending pin:", err)
+	}
+	return err
+}
+
+// commitPendingPins marks the job's pins as used by its record, in the
+// transaction that saves it.
+func commitPendingPins(ctx context.Context, tx pgx.Tx, uploadJobID string) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE pin_outbox
+		SET status = 'committed', resolved_at = NOW()
+		WHERE upload_job_id = $1 AND status = 'pending'`, uploadJobID)
+	return err
+}
+
+// ReleaseSharedPins resolves orphaned pins whose content another record or an
+// active upload uses, without unpinning it. It returns how many it released.
+func ReleaseSharedPins(grace time.Duration) (int64, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	tag, err := pool.Exec(ctx,
+		`UPDATE pin_outbox o
+		SET status = 'released', resolved_at = NOW()
+		WHERE `+orphanPinFilter+`
+			AND (EXISTS (SELECT 1 FROM records r WHERE r.ipfs_cid = o.cid)
+				OR EXISTS (
+					SELECT 1 FROM pin_outbox p
+					JOIN upload_jobs j ON j.id = p.upload_job_id
+					WHERE p.cid = o.cid AND p.id <> o.id AND p.status = 'pending'
+						AND j.status IN ('queued', 'pinning', 'saving')
+				))`, grace.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetOrphanPins returns up to limit pending pins past the grace period whose
+// record was never saved, oldest first. Call ReleaseSharedPins first so none
+// of them holds content still in use.
+func GetOrphanPins(grace time.Duration, limit int) ([]models.PendingPin, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT o.id, o.cid, o.record_id, o.attempts, o.created_at
+		FROM pin_outbox o
+		WHERE `+orphanPinFilter+`
+		ORDER BY o.created_at
+		LIMIT $2`, grace.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []models.PendingPin
+	for rows.Next() {
+		var pin models.PendingPin
+		if err := rows.Scan(&pin.ID, &pin.CID, &pin.RecordID, &pin.Attempts, &pin.CreatedAt); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+func MarkPinUnpinned(id string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE pin_outbox
+		SET status = 'unpinned', error = NULL, resolved_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, id)
+	return err
+}
+
+// RecordUnpinFailure keeps the pin pending for the next sweep.
+func RecordUnpinFailure(id string, reason string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE pin_outbox
+		SET attempts = attempts + 1, error = $2
+		WHERE id = $1`, id, reason)
+	return err
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"consentis-api/internal/models"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// insertRecordPins marks the record's content pinned by the providers, in
+// the transaction that saves the record.
+func insertRecordPins(ctx context.Context, tx pgx.Tx, recordID string, cid string, providers []string) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO record_pins (record_id, provider, cid, status, pinned_at, checked_at)
+		SELECT $1, provider, $2, 'pinned', NOW(), NOW()
+		FROM unnest($3::text[]) AS provider
+		ON CONFLICT (record_id, provider) DO NOTHING`, recordID, cid, providers)
+	return err
+}
+
+// BackfillRecordPins adds a pending row for every record that is not deleted
+// and every provider it has none for, such as records created before
+// replication or a newly added provider. It returns how many it added.
+func BackfillRecordPins(providers []string) (int64, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	tag, err := pool.Exec(ctx,
+		`INSERT INTO record_pins (record_id, provider, cid)
+		SELECT r.id, p.provider, r.ipfs_cid
+		FROM records r
+		CROSS JOIN unnest($1::text[]) AS p(provider)
+		WHERE r.deleted_at IS NULL
+		ON CONFLICT (record_id, provider) DO NOTHING`, providers)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetUnreplicatedPins returns up to limit rows of the providers that are not
+// pinned yet, skipping failed ones until their backoff, which doubles from a
+// minute with each attempt up to a day, has passed.
+func GetUnreplicatedPins(providers []string, limit int) ([]models.ProviderPin, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT p.record_id, p.provider, p.cid, p.status, p.attempts, r.name, u.wallet_address
+		FROM record_pins p
+		INNER JOIN records r ON r.id = p.record_id
+		INNER JOIN users u ON u.id = r.patient_id
+		WHERE p.provider = ANY($1)
+			AND r.deleted_at IS NULL
+			AND (p.status = 'pending'
+				OR (p.status = 'failed'
+					AND p.updated_at <= NOW() - LEAST(POWER(2, p.attempts), 1440) * INTERVAL '1 minute'))
+		ORDER BY p.attempts, p.updated_at
+		LIMIT $2`, providers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []models.ProviderPin
+	for rows.Next() {
+		var pin models.ProviderPin
+		if err := rows.Scan(&pin.RecordID, &pin.Provider, &pin.CID, &pin.Status, &pin.Attempts,
+			&pin.Name, &pin.PatientAddress); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// MarkProviderPinned records that the provider pins the record's content.
+func MarkProviderPinned(recordID string, provider string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE record_pins
+		SET status = 'pinned', attempts = 0, error = NULL, pinned_at = NOW(), checked_at = NOW()
+		WHERE record_id = $1 AND provider = $2`, recordID, provider)
+	return err
+}
+
+// RecordReplicationFailure marks the row failed, to be retried after its
+// backoff.
+func RecordReplicationFailure(recordID string, provider string, reason string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE record_pins
+		SET status = 'failed', attempts = attempts + 1, error = $3, checked_at = NOW()
+		WHERE record_id = $1 AND provider = $2`, recordID, provider, reason)
+	return err
+}
+
+// SyncProviderPins applies a listing of the provider's pins taken at
+// listedAt: rows whose CID is listed become pinned, and pinned rows whose CID
+// is not listed go back to pending so the replicator restores them. Rows
+// changed after the listing are left alone.
+func SyncProviderPins(provider string, pinned []string, listedAt time.Time) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE record_pins
+		SET status = CASE
+				WHEN cid = ANY($2) THEN 'pinned'
+				WHEN status = 'pinned' THEN 'pending'
+				ELSE status
+			END,
+			pinned_at = CASE WHEN cid = ANY($2) AND status <> 'pinned' THEN NOW() ELSE pinned_at END,
+			attempts = CASE WHEN cid = ANY($2) THEN 0 ELSE attempts END,
+			error = CASE
+				WHEN cid = ANY($2) THEN NULL
+				WHEN status = 'pinned' THEN 'pin no longer listed by the provider'
+				ELSE error
+			END,
+			checked_at = NOW()
+		WHERE provider = $1 AND updated_at < $3`, provider, pinned, listedAt)
+	return err
+}
+
+// CountRecordPinsByProvider counts the replicas of records that are not
+// deleted, by provider and status.
+func CountRecordPinsByProvider() (map[string]map[string]int64, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT p.provider, p.status, COUNT(*)
+		FROM record_pins p
+		INNER JOIN records r ON r.id = p.record_id
+		WHERE r.deleted_at IS NULL
+		GROUP BY p.provider, p.status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[string]int64)
+	for rows.Next() {
+		var provider, status string
+		var count int64
+		if err := rows.Scan(&provider, &status, &count); err != nil {
+			return nil, err
+		}
+		if counts[provider] == nil {
+			counts[provider] = make(map[string]int64)
+		}
+		counts[provider][status] = count
+	}
+	return counts, rows.Err()
+}
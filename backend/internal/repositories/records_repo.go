@@ -4,9 +4,16 @@ import (
 	"consentis-api/internal/dtos"
 	"consentis-api/internal/models"
 	"context"
+	"errors"
 	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+var ErrRecordExists = errors.New("a record with this id already exists")
+
 func CreateRecord(record models.Record, patientAddress string) error {
 	pool, err := GetDB()
 	if err != nil {
@@ -19,8 +26,24 @@ func CreateRecord(record models.Record, patientAddress string) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err := insertRecord(ctx, tx, record, patientAddress); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	log.Println("Row inserted successfully into records.")
+	return nil
+}
+
+// insertRecord inserts the record and, when the patient is new, their user
+// row. ErrRecordExists is returned when the record id is taken.
+func insertRecord(ctx context.Context, tx pgx.Tx, record models.Record, patientAddress string) error {
 	var patientId string
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 	    INSERT INTO users (wallet_address, role)
         VALUES ($1, 'patient')
         ON CONFLICT (wallet_address)
@@ -33,25 +56,25 @@ func CreateRecord(record models.Record, patientAddress string) error {
 	}
 
 	_, err = tx.Exec(ctx,
-		`INSERT INTO records (id, patient_id, name, ipfs_cid, data_to_encrypt_hash, acc_json)
-		VALUES ($1, $2, $3, $4, $5, $6)`,
-		record.ID, patientId, record.Name, record.IPFSCid, record.DataToEncryptHash, record.AccJson)
+		`INSERT INTO records (id, patient_id, name, ipfs_cid, data_to_encrypt_hash, acc_json, onchain_id_hash, content_sha256, content_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		record.ID, patientId, record.Name, record.IPFSCid, record.DataToEncryptHash, record.AccJson, record.IDHash,
+		record.ContentSHA256, record.ContentSize)
 
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrRecordExists
+		}
 		log.Println(err)
 		return err
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		log.Println(err)
-		return err
-	}
-
-	log.Println("Row inserted successfully into records.")
 	return nil
 }
 
-func GetAllRecords(researcherAddress string) ([]dtos.RecordMetadataWithConsentResponse, error) {
+// GetAccessibleRecords returns the records a researcher currently holds a
+// granted consent for, including the material needed to decrypt them.
+func GetAccessibleRecords(researcherAddress string) ([]dtos.RecordMetadataWithConsentResponse, error) {
 	pool, err := GetDB()
 	if err != nil {
 		return nil, err
@@ -67,11 +90,12 @@ func GetAllRecords(researcherAddress string) ([]dtos.RecordMetadataWithConsentRe
 			r.acc_json,
 			u.wallet_address,
 			r.created_at,
-			CASE WHEN c.researcher_address IS NOT NULL THEN c.status ELSE '' END as consent_status,
-			CASE WHEN c.researcher_address IS NOT NULL THEN c.updated_at ELSE NULL END as last_updated
+			c.status,
+			c.updated_at
 		FROM records r
 		INNER JOIN users u ON r.patient_id = u.id
-		LEFT JOIN consents c ON r.id = c.record_id AND c.researcher_address = $1
+		INNER JOIN consents c ON r.id = c.record_id AND c.researcher_address = $1
+		WHERE c.status = 'granted' AND r.deleted_at IS NULL
 		ORDER BY r.created_at DESC`, researcherAddress)
 
 	if err != nil {
@@ -100,7 +124,10 @@ func GetAllRecords(researcherAddress string) ([]dtos.RecordMetadataWithConsentRe
 	return recordsMetadata, nil
 }
 
-func GetRecordsByOwnerAddress(address string) ([]dtos.RecordsByPatientResponse, error) {
+// GetDiscoverableRecords returns non-identifying metadata for records, scoped
+// to the researcher's consent status. An empty status returns every record,
+// "none" returns records the researcher has no consent row for.
+func GetDiscoverableRecords(researcherAddress string, status string) ([]dtos.RecordDiscoveryResponse, error) {
 	pool, err := GetDB()
 	if err != nil {
 		return nil, err
@@ -108,11 +135,59 @@ func GetRecordsByOwnerAddress(address string) ([]dtos.RecordsByPatientResponse,
 
 	ctx := context.Background()
 	rows, err := pool.Query(ctx,
-		`SELECT r.id, r.name, r.ipfs_cid, r.data_to_encrypt_hash, r.acc_json, u.wallet_address, r.created_at
+		`SELECT
+			r.id,
+			r.name,
+			r.created_at,
+			COALESCE(c.status, '') as consent_status,
+			c.updated_at as last_updated
+		FROM records r
+		LEFT JOIN consents c ON r.id = c.record_id AND c.researcher_address = $1
+		WHERE r.deleted_at IS NULL
+			AND ($2::text = ''
+				OR ($2::text = 'none' AND c.researcher_address IS NULL)
+				OR c.status = $2::text)
+		ORDER BY r.created_at DESC`, researcherAddress, status)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []dtos.RecordDiscoveryResponse
+	for rows.Next() {
+		var record dtos.RecordDiscoveryResponse
+		if err := rows.Scan(
+			&record.Id,
+			&record.Name,
+			&record.CreatedAt,
+			&record.ConsentStatus,
+			&record.LastUpdatedConsent,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// GetRecordsByOwnerAddress returns the patient's records. Soft-deleted
+// records are only included when includeDeleted is set.
+func GetRecordsByOwnerAddress(address string, includeDeleted bool) ([]dtos.RecordsByPatientResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT r.id, r.name, r.ipfs_cid, r.data_to_encrypt_hash, r.acc_json, u.wallet_address, r.created_at, r.deleted_at,
+			r.content_sha256, r.content_size, r.registration_status, r.onchain_owner, r.registration_tx_hash, r.registration_block_number,
+			r.pin_status, r.pin_checked_at, r.pin_repinned_at
 		FROM records r
 		INNER JOIN users u ON r.patient_id = u.id
-		WHERE u.wallet_address = $1
-		ORDER BY r.created_at DESC`, address)
+		WHERE u.wallet_address = $1 AND ($2 OR r.deleted_at IS NULL)
+		ORDER BY r.created_at DESC`, address, includeDeleted)
 
 	if err != nil {
 		return nil, err
@@ -130,6 +205,16 @@ func GetRecordsByOwnerAddress(address string) ([]dtos.RecordsByPatientResponse,
 			&record.AccJson,
 			&record.PatientAddress,
 			&record.CreatedAt,
+			&record.DeletedAt,
+			&record.ContentSHA256,
+			&record.ContentSize,
+			&record.Registration.Status,
+			&record.Registration.Owner,
+			&record.Registration.TxHash,
+			&record.Registration.BlockNumber,
+			&record.Pin.Status,
+			&record.Pin.CheckedAt,
+			&record.Pin.RepinnedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -137,3 +222,367 @@ func GetRecordsByOwnerAddress(address string) ([]dtos.RecordsByPatientResponse,
 	}
 	return records, nil
 }
+
+func GetRecordByID(id string) (*dtos.RecordDetailResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var record dtos.RecordDetailResponse
+	err = pool.QueryRow(ctx,
+		`SELECT r.id, r.name, r.ipfs_cid, r.data_to_encrypt_hash, r.acc_json, u.wallet_address, r.created_at,
+			r.content_sha256, r.content_size
+		FROM records r
+		INNER JOIN users u ON r.patient_id = u.id
+		WHERE r.id = $1 AND r.deleted_at IS NULL`, id).Scan(
+		&record.Id,
+		&record.Name,
+		&record.IPFSCid,
+		&record.DataToEncryptHash,
+		&record.AccJson,
+		&record.PatientAddress,
+		&record.CreatedAt,
+		&record.ContentSHA256,
+		&record.ContentSize,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		log.Println("Error fetching record:", err)
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// GetRecordOwnerAddress returns the wallet of the patient owning a record,
+// soft deleted records included.
+func GetRecordOwnerAddress(id string) (string, bool, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return "", false, err
+	}
+
+	ctx := context.Background()
+	var owner string
+	err = pool.QueryRow(ctx,
+		`SELECT u.wallet_address
+		FROM records r
+		INNER JOIN users u ON r.patient_id = u.id
+		WHERE r.id = $1`, id).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", false, nil
+		}
+		log.Println("Error fetching record owner:", err)
+		return "", false, err
+	}
+
+	return owner, true, nil
+}
+
+// SoftDeleteRecord marks the record as deleted. When revokeConsents is set,
+// every consent that is not already revoked is marked revoked in the same
+// transaction; consent logs confirmed later leave the consents of a deleted
+// record alone. The researchers whose consent was granted are returned,
+// as those still need a revokeConsent transaction on-chain.
+func SoftDeleteRecord(id string, revokeConsents bool) ([]string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`UPDATE records SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+
+	activeResearchers := []string{}
+	if revokeConsents {
+		rows, err := tx.Query(ctx,
+			`UPDATE consents c
+			SET status = 'revoked'
+			FROM (
+				SELECT id, status AS previous_status
+				FROM consents
+				WHERE record_id = $1 AND status <> 'revoked'
+				FOR UPDATE
+			) prev
+			WHERE c.id = prev.id
+			RETURNING c.researcher_address, prev.previous_status`, id)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+
+		for rows.Next() {
+			var researcher, previousStatus string
+			if err := rows.Scan(&researcher, &previousStatus); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if previousStatus == "granted" {
+				activeResearchers = append(activeResearchers, researcher)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	log.Println("Record soft deleted:", id)
+	return activeResearchers, nil
+}
+
+// GetRecordIDsWithoutHash returns the records created before the on-chain id
+// hash was stored.
+func GetRecordIDsWithoutHash() ([]string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx, `SELECT id FROM records WHERE onchain_id_hash IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func SetRecordIDHash(id string, idHash string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE records SET onchain_id_hash = $2 WHERE id = $1 AND onchain_id_hash IS NULL`, id, idHash)
+	if err != nil {
+		log.Println("Error saving record id hash:", err)
+		return err
+	}
+	return nil
+}
+
+// ApplyRecordRegistration stores the on-chain registration of the record whose
+// id hashes to reg.RecordIDHash, flagging it owner_mismatch when the wallet
+// that registered it is not the record's patient. It returns the record id and
+// the resulting registration status, or found = false when no record matches.
+func ApplyRecordRegistration(reg models.RecordRegistration) (recordID string, status string, found bool, err error) {
+	pool, err := GetDB()
+	if err != nil {
+		return "", "", false, err
+	}
+
+	recordID, status, found, err = applyRecordRegistration(context.Background(), pool, reg)
+	if err != nil {
+		log.Println("Error applying record registration:", err)
+	}
+	return recordID, status, found, err
+}
+
+// queryRower is a pool or a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func applyRecordRegistration(ctx context.Context, db queryRower, reg models.RecordRegistration) (string, string, bool, error) {
+	var recordID, status string
+	err := db.QueryRow(ctx,
+		`UPDATE records r
+		SET onchain_owner = $2,
+			registration_tx_hash = $3,
+			registration_block_number = $4,
+			registration_block_hash = $5,
+			registration_status = CASE
+				WHEN LOWER(u.wallet_address) = LOWER($2) THEN 'registered'
+				ELSE 'owner_mismatch'
+			END
+		FROM users u
+		WHERE u.id = r.patient_id AND r.onchain_id_hash = $1
+		RETURNING r.id, r.registration_status`,
+		reg.RecordIDHash, reg.Owner, reg.TxHash, int64(reg.BlockNumber), reg.BlockHash).Scan(&recordID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return recordID, status, true, nil
+}
+
+// RollbackRecordRegistration clears a registration whose transaction was
+// reorged out, putting the record back to pending. It returns the id of the
+// record, or an empty string when no record was registered by the transaction.
+func RollbackRecordRegistration(txHash string) (string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	var recordID string
+	err = pool.QueryRow(ctx,
+		`UPDATE records
+		SET onchain_owner = NULL,
+			registration_tx_hash = NULL,
+			registration_block_number = NULL,
+			registration_block_hash = NULL,
+			registration_status = 'pending'
+		WHERE registration_tx_hash = $1
+		RETURNING id`, txHash).Scan(&recordID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		log.Println("Error rolling back record registration:", err)
+		return "", err
+	}
+	return recordID, nil
+}
+
+// MarkUnregisteredRecords flags the pending records created more than window
+// ago and returns their ids.
+func MarkUnregisteredRecords(window time.Duration) ([]string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`UPDATE records SET registration_status = 'unregistered'
+		WHERE registration_status = 'pending' AND created_at < NOW() - make_interval(secs => $1)
+		RETURNING id`, window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetRecordPins returns the content of every record that is not deleted, for
+// the pin health check.
+func GetRecordPins() ([]models.RecordPin, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT r.id, r.ipfs_cid, r.name, u.wallet_address
+		FROM records r
+		INNER JOIN users u ON r.patient_id = u.id
+		WHERE r.deleted_at IS NULL
+		ORDER BY r.created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []models.RecordPin
+	for rows.Next() {
+		var pin models.RecordPin
+		if err := rows.Scan(&pin.RecordID, &pin.CID, &pin.Name, &pin.PatientAddress); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// SetRecordPinStatus stores the result of a pin health check for the records
+// holding any of the CIDs. repinned notes that the content was restored.
+func SetRecordPinStatus(cids []string, status string, repinned bool) error {
+	if len(cids) == 0 {
+		return nil
+	}
+
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = pool.Exec(ctx,
+		`UPDATE records
+		SET pin_status = $2,
+			pin_checked_at = NOW(),
+			pin_repinned_at = CASE WHEN $3 THEN NOW() ELSE pin_repinned_at END
+		WHERE ipfs_cid = ANY($1) AND deleted_at IS NULL`, cids, status, repinned)
+	return err
+}
+
+// CountRecordsByPinStatus returns how many records that are not deleted have
+// each pin status.
+func CountRecordsByPinStatus() (map[string]int64, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`SELECT pin_status, COUNT(*)
+		FROM records
+		WHERE deleted_at IS NULL
+		GROUP BY pin_status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
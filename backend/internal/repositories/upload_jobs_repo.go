@@ -0,0 +1,331 @@
+package repositories
+
+import (
+	"consentis-api/internal/dtos"
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uploadJobColumns = `
+	id, record_id, status, attempts, content_size, ipfs_cid, content_sha256, error,
+	created_at, updated_at, completed_at`
+
+const uploadJobWorkColumns = `
+	id, record_id, patient_address, name, data_to_encrypt_hash, acc_json, staging_path,
+	content_size, status, attempts, COALESCE(ipfs_cid, ''), COALESCE(content_sha256, '')`
+
+var (
+	ErrUploadExists    = errors.New("an upload with this record id or idempotency key already exists")
+	ErrUploadLeaseLost = errors.New("upload job was claimed again after its lease expired")
+)
+
+// leaseFence restricts a job update to the attempt that claimed it: every
+// claim increments attempts, so a worker whose lease expired and whose job was
+// claimed again, completed or failed no longer matches.
+const leaseFence = `id = $1 AND attempts = $2 AND status IN ('pinning', 'saving')`
+
+// CreateUploadJob queues an upload whose content waits at job.StagingPath.
+// It returns ErrUploadExists when the record already has an upload that has
+// not failed, or the patient already used the idempotency key.
+func CreateUploadJob(job models.UploadJob) (*dtos.UploadJobResponse, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	row := pool.QueryRow(ctx,
+		`INSERT INTO upload_jobs (record_id, record_id_hash, patient_address, name, data_to_encrypt_hash, acc_json,
+			staging_path, content_size, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+		RETURNING `+uploadJobColumns,
+		job.RecordID, helpers.RecordIDHash(job.RecordID), job.PatientAddress, job.Name, job.DataToEncryptHash, job.AccJson,
+		job.StagingPath, job.ContentSize, job.IdempotencyKey)
+
+	created, err := scanUploadJob(row)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUploadExists
+		}
+		log.Println("Error creating upload job:", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// FindPriorUpload returns the upload the patient queued under idempotencyKey
+// when one is given and used. Otherwise it returns the record's latest upload
+// that has not failed or, failing that, the record itself, noting whether the
+// record was soft-deleted. It returns nil when there is none.
+func FindPriorUpload(patientAddress string, idempotencyKey string, recordID string) (*models.PriorUpload, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var prior models.PriorUpload
+	if idempotencyKey != "" {
+		err = pool.QueryRow(ctx,
+			`SELECT j.id, j.record_id, j.patient_address, j.data_to_encrypt_hash, j.idempotency_key,
+				r.deleted_at IS NOT NULL
+			FROM upload_jobs j
+			LEFT JOIN records r ON r.id = j.record_id
+			WHERE LOWER(j.patient_address) = LOWER($1) AND j.idempotency_key = $2`,
+			patientAddress, idempotencyKey).Scan(&prior.JobID, &prior.RecordID, &prior.PatientAddress,
+			&prior.DataToEncryptHash, &prior.IdempotencyKey, &prior.RecordDeleted)
+		if err == nil {
+			return &prior, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	err = pool.QueryRow(ctx,
+		`SELECT j.id, j.record_id, j.patient_address, j.data_to_encrypt_hash, COALESCE(j.idempotency_key, ''),
+			r.deleted_at IS NOT NULL
+		FROM upload_jobs j
+		LEFT JOIN records r ON r.id = j.record_id
+		WHERE j.record_id = $1 AND j.status <> 'failed'
+		ORDER BY j.created_at DESC
+		LIMIT 1`, recordID).Scan(&prior.JobID, &prior.RecordID, &prior.PatientAddress,
+		&prior.DataToEncryptHash, &prior.IdempotencyKey, &prior.RecordDeleted)
+	if err == nil {
+		return &prior, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	err = pool.QueryRow(ctx,
+		`SELECT r.id, u.wallet_address, r.data_to_encrypt_hash, r.ipfs_cid, r.deleted_at IS NOT NULL
+		FROM records r
+		INNER JOIN users u ON r.patient_id = u.id
+		WHERE r.id = $1`, recordID).Scan(&prior.RecordID, &prior.PatientAddress, &prior.DataToEncryptHash,
+		&prior.IPFSCid, &prior.RecordDeleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prior, nil
+}
+
+// GetUploadJob returns the job and the address of the patient who queued it,
+// or nil when no job has the id.
+func GetUploadJob(id string) (*dtos.UploadJobResponse, string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx := context.Background()
+	var patientAddress string
+	row := pool.QueryRow(ctx,
+		`SELECT `+uploadJobColumns+`, patient_address
+		FROM upload_jobs
+		WHERE id = $1`, id)
+
+	var job dtos.UploadJobResponse
+	err = row.Scan(
+		&job.Id, &job.RecordId, &job.Status, &job.Attempts, &job.ContentSize, &job.IPFSCid,
+		&job.ContentSHA256, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt,
+		&patientAddress,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &job, patientAddress, nil
+}
+
+// ClaimUploadJob leases the oldest job that is due: queued ones past their
+// backoff, and ones whose worker lost its lease, such as after a crash. The
+// job moves to pinning, or to saving when its content is already pinned.
+// It returns nil when no job is due.
+func ClaimUploadJob(lease time.Duration, maxAttempts int) (*models.UploadJob, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var job models.UploadJob
+	err = pool.QueryRow(ctx,
+		`UPDATE upload_jobs
+		SET status = CASE WHEN ipfs_cid IS NULL THEN 'pinning' ELSE 'saving' END,
+			attempts = attempts + 1,
+			locked_until = NOW() + $1 * INTERVAL '1 second'
+		WHERE id = (
+			SELECT id FROM upload_jobs
+			WHERE attempts < $2
+				AND ((status = 'queued' AND available_at <= NOW())
+					OR (status IN ('pinning', 'saving') AND locked_until < NOW()))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+uploadJobWorkColumns,
+		lease.Seconds(), maxAttempts).Scan(
+		&job.ID, &job.RecordID, &job.PatientAddress, &job.Name, &job.DataToEncryptHash, &job.AccJson,
+		&job.StagingPath, &job.ContentSize, &job.Status, &job.Attempts, &job.IPFSCid, &job.ContentSHA256,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// MarkUploadJobPinned records the verified CID so a retry only has to save
+// the record. Like the other transitions of a claimed job it takes the
+// attempt that claimed it and returns ErrUploadLeaseLost when the job moved
+// on without it.
+func MarkUploadJobPinned(id string, attempt int, cid string, contentSHA256 string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	result, err := pool.Exec(ctx,
+		`UPDATE upload_jobs
+		SET status = 'saving', ipfs_cid = $3, content_sha256 = $4
+		WHERE `+leaseFence, id, attempt, cid, contentSHA256)
+	return leaseResult(result, err)
+}
+
+// CompleteUploadJob inserts the record, commits the job's pending pins and
+// marks the job completed in one transaction, so a job is never completed
+// without its record or retried after it. Chain logs about the record that
+// arrived while the upload was open are applied with it. pinnedBy lists the
+// storage providers known to pin the content; the replicator checks the
+// others.
+func CompleteUploadJob(id string, attempt int, record models.Record, patientAddress string, pinnedBy []string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the job first: the indexer defers logs only while it is open.
+	result, err := tx.Exec(ctx,
+		`UPDATE upload_jobs
+		SET status = 'completed', error = NULL, locked_until = NULL, completed_at = NOW()
+		WHERE `+leaseFence, id, attempt)
+	if err := leaseResult(result, err); err != nil {
+		return err
+	}
+
+	if err := insertRecord(ctx, tx, record, patientAddress); err != nil {
+		return err
+	}
+	if err := commitPendingPins(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := insertRecordPins(ctx, tx, record.ID, record.IPFSCid, pinnedBy); err != nil {
+		return err
+	}
+	if err := applyDeferredLogs(ctx, tx, record.ID, record.IDHash); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetryUploadJob queues the job again once delay has passed, keeping reason
+// as its last error.
+func RetryUploadJob(id string, attempt int, reason string, delay time.Duration) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	result, err := pool.Exec(ctx,
+		`UPDATE upload_jobs
+		SET status = 'queued', error = $3, locked_until = NULL,
+			available_at = NOW() + $4 * INTERVAL '1 second'
+		WHERE `+leaseFence, id, attempt, reason, delay.Seconds())
+	return leaseResult(result, err)
+}
+
+func FailUploadJob(id string, attempt int, reason string) error {
+	pool, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	result, err := pool.Exec(ctx,
+		`UPDATE upload_jobs
+		SET status = 'failed', error = $3, locked_until = NULL, completed_at = NOW()
+		WHERE `+leaseFence, id, attempt, reason)
+	return leaseResult(result, err)
+}
+
+// leaseResult turns an update of a claimed job that matched no row into
+// ErrUploadLeaseLost.
+func leaseResult(result pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUploadLeaseLost
+	}
+	return nil
+}
+
+// FailAbandonedUploadJobs fails jobs whose worker lost its lease on the last
+// allowed attempt, which ClaimUploadJob no longer picks up. It returns their
+// staging paths so the content can be removed.
+func FailAbandonedUploadJobs(maxAttempts int) ([]string, error) {
+	pool, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rows, err := pool.Query(ctx,
+		`UPDATE upload_jobs
+		SET status = 'failed', locked_until = NULL, completed_at = NOW(),
+			error = 'upload was interrupted too many times'
+		WHERE status IN ('pinning', 'saving')
+			AND locked_until < NOW()
+			AND attempts >= $1
+		RETURNING staging_path`, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func scanUploadJob(row pgx.Row) (*dtos.UploadJobResponse, error) {
+	var job dtos.UploadJobResponse
+	if err := row.Scan(
+		&job.Id, &job.RecordId, &job.Status, &job.Attempts, &job.ContentSize, &job.IPFSCid,
+		&job.ContentSHA256, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
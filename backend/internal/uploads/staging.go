@@ -0,0 +1,55 @@
+package uploads
+
+import (
+	"consentis-api/internal/ipfs"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+const DefaultStagingDir = "data/uploads"
+
+// Stage copies r, at most ipfs.MaxFileSize bytes, to a new file in
+// UPLOAD_STAGING_DIR where it waits for an upload worker. Every replica
+// running workers must see the same directory.
+func Stage(r io.Reader) (string, int64, error) {
+	dir := os.Getenv("UPLOAD_STAGING_DIR")
+	if dir == "" {
+		dir = DefaultStagingDir
+	}
+	return stageIn(dir, r)
+}
+
+func stageIn(dir string, r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", 0, fmt.Errorf("create staging dir: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("create staging file: %w", err)
+	}
+
+	size, err := io.Copy(file, io.LimitReader(r, ipfs.MaxFileSize+1))
+	if err == nil && size > ipfs.MaxFileSize {
+		err = fmt.Errorf("%w of %d bytes", ipfs.ErrFileTooLarge, ipfs.MaxFileSize)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", 0, err
+	}
+
+	return file.Name(), size, nil
+}
+
+// RemoveStaged deletes staged content once its job no longer needs it.
+func RemoveStaged(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error removing staged upload %s: %v", path, err)
+	}
+}
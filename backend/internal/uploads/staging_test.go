@@ -0,0 +1,55 @@
+package uploads
+
+import (
+	"bytes"
+	"consentis-api/internal/ipfs"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestStageIn(t *testing.T) {
+	dir := t.TempDir()
+
+	path, size, err := stageIn(dir, bytes.NewReader([]byte("ciphertext")))
+	if err != nil {
+		t.Fatalf("stageIn: %v", err)
+	}
+	if size != int64(len("ciphertext")) {
+		t.Errorf("Expected size %d, got %d", len("ciphertext"), size)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read staged file: %v", err)
+	}
+	if string(content) != "ciphertext" {
+		t.Errorf("Expected staged content %q, got %q", "ciphertext", content)
+	}
+
+	RemoveStaged(path)
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected staged file to be removed, got %v", err)
+	}
+}
+
+func TestStageIn_TooLarge(t *testing.T) {
+	dir := t.TempDir()
+
+	_, _, err := stageIn(dir, io.LimitReader(zeroReader{}, ipfs.MaxFileSize+1))
+	if !errors.Is(err, ipfs.ErrFileTooLarge) {
+		t.Fatalf("Expected ErrFileTooLarge, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected the partial file to be removed, found %d files", len(entries))
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
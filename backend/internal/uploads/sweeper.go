@@ -0,0 +1,106 @@
+package uploads
+
+import (
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultOrphanGracePeriod = time.Hour
+	sweepInterval            = 5 * time.Minute
+	sweepBatchSize           = 100
+)
+
+// SweepOrphanPins periodically unpins content whose record was never saved:
+// pins noted by an upload job that failed or vanished more than
+// PIN_ORPHAN_GRACE_PERIOD ago. Content another record uses stays pinned.
+// Pins that fail to unpin are retried on the next sweep.
+func SweepOrphanPins(ctx context.Context) {
+	grace := getOrphanGracePeriod()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepOrphanPins(ctx, grace)
+		}
+	}
+}
+
+func sweepOrphanPins(ctx context.Context, grace time.Duration) {
+	released, err := repositories.ReleaseSharedPins(grace)
+	if err != nil {
+		log.Printf("Error releasing shared pins: %v", err)
+		return
+	}
+	if released > 0 {
+		log.Printf("Released %d orphaned pins whose content is still in use", released)
+	}
+
+	pins, err := repositories.GetOrphanPins(grace, sweepBatchSize)
+	if err != nil {
+		log.Printf("Error loading orphaned pins: %v", err)
+		return
+	}
+	if len(pins) == 0 {
+		return
+	}
+
+	storage, err := ipfs.GetStorage()
+	if err != nil {
+		log.Printf("Error initializing storage: %v", err)
+		return
+	}
+
+	for _, pin := range pins {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := unpinOrphan(ctx, storage, pin.CID); err != nil {
+			log.Printf("Error unpinning orphaned %s of record %s: %v", pin.CID, pin.RecordID, err)
+			if err := repositories.RecordUnpinFailure(pin.ID, err.Error()); err != nil {
+				log.Printf("Error recording unpin failure: %v", err)
+			}
+			continue
+		}
+
+		if err := repositories.MarkPinUnpinned(pin.ID); err != nil {
+			log.Printf("Error marking %s unpinned: %v", pin.CID, err)
+			continue
+		}
+		log.Printf("Unpinned orphaned %s of record %s", pin.CID, pin.RecordID)
+	}
+}
+
+// unpinOrphan removes the pin. Content that is not pinned, because the upload
+// never reached the provider or an earlier sweep got to it, counts as done.
+func unpinOrphan(ctx context.Context, storage ipfs.Storage, cid string) error {
+	err := storage.Unpin(ctx, cid)
+	if errors.Is(err, ipfs.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func getOrphanGracePeriod() time.Duration {
+	value := os.Getenv("PIN_ORPHAN_GRACE_PERIOD")
+	if value == "" {
+		return defaultOrphanGracePeriod
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace <= 0 {
+		log.Printf("Invalid PIN_ORPHAN_GRACE_PERIOD %q, using %s", value, defaultOrphanGracePeriod)
+		return defaultOrphanGracePeriod
+	}
+	return grace
+}
@@ -0,0 +1,86 @@
+package uploads
+
+import (
+	"consentis-api/internal/ipfs"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// unpinStorage is a Storage whose Unpin returns err and records the CIDs it
+// was asked to unpin.
+type unpinStorage struct {
+	err      error
+	unpinned []string
+}
+
+func (s *unpinStorage) Put(ctx context.Context, r io.Reader, name string, meta map[string]string) (*ipfs.PutResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *unpinStorage) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return nil, ipfs.ErrNotFound
+}
+
+func (s *unpinStorage) Unpin(ctx context.Context, cid string) error {
+	s.unpinned = append(s.unpinned, cid)
+	return s.err
+}
+
+func (s *unpinStorage) Stat(ctx context.Context, cid string) (*ipfs.PinStat, error) {
+	return nil, ipfs.ErrNotFound
+}
+
+func (s *unpinStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestUnpinOrphan(t *testing.T) {
+	unavailable := &ipfs.PinataError{StatusCode: 503, Err: ipfs.ErrTransient}
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"Unpinned", nil, nil},
+		{"Never pinned", ipfs.ErrNotFound, nil},
+		{"Provider unavailable", unavailable, ipfs.ErrTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &unpinStorage{err: tt.err}
+			err := unpinOrphan(context.Background(), storage, "bafkreiorphan")
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(storage.unpinned) != 1 || storage.unpinned[0] != "bafkreiorphan" {
+				t.Errorf("Expected bafkreiorphan to be unpinned, got %v", storage.unpinned)
+			}
+		})
+	}
+}
+
+func TestGetOrphanGracePeriod(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultOrphanGracePeriod},
+		{"30m", 30 * time.Minute},
+		{"soon", defaultOrphanGracePeriod},
+		{"-1h", defaultOrphanGracePeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("PIN_ORPHAN_GRACE_PERIOD", tt.value)
+			if got := getOrphanGracePeriod(); got != tt.want {
+				t.Errorf("getOrphanGracePeriod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package uploads
+
+import (
+	"consentis-api/internal/helpers"
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/models"
+	"consentis-api/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultMaxAttempts = 5
+	// A job is handed to another worker when its lease runs out, so an
+	// attempt is cancelled shortly before that.
+	jobLease       = 15 * time.Minute
+	attemptTimeout = jobLease - time.Minute
+	pollInterval   = 5 * time.Second
+	retryBaseDelay = 10 * time.Second
+	maxRetryDelay  = 10 * time.Minute
+)
+
+var (
+	errStagedContentMissing = errors.New("staged upload content is missing")
+	errSaveRecord           = errors.New("save record")
+)
+
+var wake = make(chan struct{}, 1)
+
+// Notify wakes an idle worker for a newly queued job instead of waiting for
+// the next poll.
+func Notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWorkers runs UPLOAD_WORKERS workers until ctx is cancelled. Each one
+// pins the staged content of a queued job and then inserts its record. Failed
+// attempts are retried with backoff up to UPLOAD_MAX_ATTEMPTS times, unless
+// retrying cannot help. Jobs are claimed with row locks, so workers in
+// several replicas share one queue.
+func StartWorkers(ctx context.Context) {
+	workers := getEnvInt("UPLOAD_WORKERS", defaultWorkers)
+	maxAttempts := getEnvInt("UPLOAD_MAX_ATTEMPTS", defaultMaxAttempts)
+	log.Printf("Starting %d upload workers...", workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWorker(ctx, maxAttempts)
+		}()
+	}
+	wg.Wait()
+}
+
+func runWorker(ctx context.Context, maxAttempts int) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && processNext(ctx, maxAttempts) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+			failAbandoned(maxAttempts)
+		}
+	}
+}
+
+// processNext runs the next due job and reports whether there was one.
+func processNext(ctx context.Context, maxAttempts int) bool {
+	job, err := repositories.ClaimUploadJob(jobLease, maxAttempts)
+	if err != nil {
+		log.Printf("Error claiming upload job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+	err = runJob(attemptCtx, job)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: hand the job straight back to the queue.
+		if err := repositories.RetryUploadJob(job.ID, job.Attempts, "Upload was interrupted, retrying", 0); err != nil {
+			log.Printf("Error requeuing upload job %s: %v", job.ID, err)
+		}
+		return false
+	}
+	finish(job, err, maxAttempts)
+	return true
+}
+
+// runJob pins the staged content unless an earlier attempt already did, then
+// saves the record.
+func runJob(ctx context.Context, job *models.UploadJob) error {
+	// Replicas pinned by an earlier attempt are not known here; the
+	// replicator finds them.
+	var pinnedBy []string
+	if job.IPFSCid == "" {
+		storage, err := ipfs.GetStorage()
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(job.StagingPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return errStagedContentMissing
+		}
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Note the pin before uploading, so content pinned by an attempt
+		// that dies before saving the record can still be found and
+		// unpinned by the orphan sweeper.
+		expected := ipfs.NewCIDWriter()
+		if _, err := io.Copy(expected, file); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := repositories.RecordPendingPin(job.ID, job.RecordID, expected.CID()); err != nil {
+			return fmt.Errorf("%w: %w", errSaveRecord, err)
+		}
+
+		upload, err := ipfs.PutVerified(ctx, storage, file, job.Name, map[string]string{
+			"patient": job.PatientAddress,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Upload job %s pinned %s", job.ID, upload.CID)
+
+		job.IPFSCid = upload.CID
+		pinnedBy = upload.Replicas
+		job.ContentSHA256 = upload.SHA256
+		if err := repositories.MarkUploadJobPinned(job.ID, job.Attempts, upload.CID, upload.SHA256); err != nil {
+			return fmt.Errorf("%w: %w", errSaveRecord, err)
+		}
+	}
+
+	record := models.Record{
+		ID:                job.RecordID,
+		IDHash:            helpers.RecordIDHash(job.RecordID),
+		Name:              job.Name,
+		IPFSCid:           job.IPFSCid,
+		DataToEncryptHash: job.DataToEncryptHash,
+		AccJson:           job.AccJson,
+		ContentSHA256:     job.ContentSHA256,
+		ContentSize:       job.ContentSize,
+	}
+	if err := repositories.CompleteUploadJob(job.ID, job.Attempts, record, job.PatientAddress, pinnedBy); err != nil {
+		return fmt.Errorf("%w: %w", errSaveRecord, err)
+	}
+	return nil
+}
+
+// finish records the outcome of an attempt. The staged content is kept until
+// the job can no longer be retried.
+func finish(job *models.UploadJob, err error, maxAttempts int) {
+	if err == nil {
+		log.Printf("Upload job %s completed record %s", job.ID, job.RecordID)
+		RemoveStaged(job.StagingPath)
+		return
+	}
+
+	if errors.Is(err, repositories.ErrUploadLeaseLost) {
+		// Another worker owns the job now, leave it and its content alone.
+		log.Printf("Upload job %s attempt %d lost its lease", job.ID, job.Attempts)
+		return
+	}
+
+	log.Printf("Upload job %s attempt %d failed: %v", job.ID, job.Attempts, err)
+	reason := failureReason(err)
+	if isPermanent(err) || job.Attempts >= maxAttempts {
+		if err := repositories.FailUploadJob(job.ID, job.Attempts, reason); err != nil {
+			log.Printf("Error failing upload job %s: %v", job.ID, err)
+			return
+		}
+		RemoveStaged(job.StagingPath)
+		return
+	}
+
+	if err := repositories.RetryUploadJob(job.ID, job.Attempts, reason, retryDelay(job.Attempts, err)); err != nil {
+		log.Printf("Error requeuing upload job %s: %v", job.ID, err)
+	}
+}
+
+func failAbandoned(maxAttempts int) {
+	paths, err := repositories.FailAbandonedUploadJobs(maxAttempts)
+	if err != nil {
+		log.Printf("Error failing abandoned upload jobs: %v", err)
+		return
+	}
+	for _, path := range paths {
+		RemoveStaged(path)
+	}
+}
+
+// isPermanent reports whether retrying the job cannot succeed.
+func isPermanent(err error) bool {
+	return errors.Is(err, ipfs.ErrCIDMismatch) ||
+		errors.Is(err, ipfs.ErrFileTooLarge) ||
+		errors.Is(err, errStagedContentMissing) ||
+		errors.Is(err, repositories.ErrRecordExists) ||
+		repositories.IsPermanentError(err)
+}
+
+// failureReason describes the failure to the patient polling the job,
+// without the provider's or the database's own messages.
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ipfs.ErrCIDMismatch):
+		return "IPFS upload could not be verified"
+	case errors.Is(err, ipfs.ErrFileTooLarge):
+		return "File exceeds the upload size limit"
+	case errors.Is(err, ipfs.ErrRateLimited):
+		return "IPFS provider is rate limiting uploads"
+	case errors.Is(err, ipfs.ErrQuorumNotReached):
+		return "Not enough storage providers pinned the upload"
+	case errors.Is(err, errStagedContentMissing):
+		return "Uploaded content is no longer available, upload the record again"
+	case errors.Is(err, repositories.ErrRecordExists):
+		return "A record with this id already exists"
+	case errors.Is(err, errSaveRecord):
+		return "Failed to save record"
+	}
+	return "IPFS upload failed"
+}
+
+// retryDelay doubles from retryBaseDelay up to maxRetryDelay, jittered over
+// the upper half, and waits at least as long as a rate limit asks.
+func retryDelay(attempt int, err error) time.Duration {
+	delay := maxRetryDelay
+	if attempt < 16 {
+		delay = min(retryBaseDelay<<max(attempt-1, 0), maxRetryDelay)
+	}
+	half := delay / 2
+	delay = half + rand.N(half+1)
+
+	var pinataErr *ipfs.PinataError
+	if errors.As(err, &pinataErr) {
+		delay = max(delay, pinataErr.RetryAfter)
+	}
+	return delay
+}
+
+func getEnvInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return n
+}
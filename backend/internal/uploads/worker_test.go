@@ -0,0 +1,76 @@
+package uploads
+
+import (
+	"consentis-api/internal/ipfs"
+	"consentis-api/internal/repositories"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"CID mismatch", fmt.Errorf("%w: computed other", ipfs.ErrCIDMismatch), true},
+		{"Too large", ipfs.ErrFileTooLarge, true},
+		{"Staged content missing", errStagedContentMissing, true},
+		{"Record exists", fmt.Errorf("%w: %w", errSaveRecord, repositories.ErrRecordExists), true},
+		{"Constraint violation", fmt.Errorf("%w: %w", errSaveRecord, &pgconn.PgError{Code: "23503"}), true},
+		{"Rate limited", &ipfs.PinataError{StatusCode: 429, Err: ipfs.ErrRateLimited}, false},
+		{"Provider unavailable", &ipfs.PinataError{StatusCode: 503, Err: ipfs.ErrTransient}, false},
+		{"Database unavailable", fmt.Errorf("%w: %w", errSaveRecord, errors.New("connection refused")), false},
+		{"Quorum not reached", &ipfs.QuorumError{Quorum: 2, Failures: map[string]error{"kubo": ipfs.ErrCIDMismatch}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermanent(tt.err); got != tt.want {
+				t.Errorf("isPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"CID mismatch", ipfs.ErrCIDMismatch, "IPFS upload could not be verified"},
+		{"Rate limited", &ipfs.PinataError{StatusCode: 429, Message: "slow down", Err: ipfs.ErrRateLimited}, "IPFS provider is rate limiting uploads"},
+		{"Provider error", &ipfs.PinataError{StatusCode: 500, Message: "internal details", Err: ipfs.ErrTransient}, "IPFS upload failed"},
+		{"Database error", fmt.Errorf("%w: %w", errSaveRecord, errors.New("connection refused")), "Failed to save record"},
+		{"Record exists", fmt.Errorf("%w: %w", errSaveRecord, repositories.ErrRecordExists), "A record with this id already exists"},
+		{"Quorum not reached", &ipfs.QuorumError{Quorum: 2}, "Not enough storage providers pinned the upload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureReason(tt.err); got != tt.want {
+				t.Errorf("failureReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 20; attempt++ {
+		delay := retryDelay(attempt, errors.New("unavailable"))
+		upper := min(retryBaseDelay<<min(attempt-1, 15), maxRetryDelay)
+		if delay < upper/2 || delay > upper {
+			t.Errorf("attempt %d: delay %s outside [%s, %s]", attempt, delay, upper/2, upper)
+		}
+	}
+
+	rateLimited := &ipfs.PinataError{StatusCode: 429, RetryAfter: time.Hour, Err: ipfs.ErrRateLimited}
+	if delay := retryDelay(1, rateLimited); delay != time.Hour {
+		t.Errorf("Expected the Retry-After of %s, got %s", time.Hour, delay)
+	}
+}
@@ -26,7 +26,8 @@ export function RecordUploadForm({
   const isLoading =
     status === "encrypting" ||
     status === "uploading" ||
-    status === "registering";
+    status === "registering" ||
+    status === "processing";
   const isDisabled = isLoading || status === "success";
 
   const onDrop = useCallback((acceptedFiles: File[]) => {
@@ -135,7 +136,9 @@ export function RecordUploadForm({
                   ? "Uploading..."
                   : status === "registering"
                     ? "Registering..."
-                    : "Upload Record"}
+                    : status === "processing"
+                      ? "Processing..."
+                      : "Upload Record"}
             </Button>
           </>
         )}
@@ -106,6 +106,15 @@ describe("RecordUploadForm", () => {
       ).toBeInTheDocument();
     });
 
+    it("shows processing status", () => {
+      mockStatus = "processing";
+
+      render(<RecordUploadForm patientAddress="0xPatient" />);
+      expect(
+        screen.getByRole("button", { name: /Processing/ })
+      ).toBeInTheDocument();
+    });
+
     it("shows success message", () => {
       mockStatus = "success";
 
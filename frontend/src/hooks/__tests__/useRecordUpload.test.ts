@@ -29,8 +29,10 @@ vi.mock("@/services/lit", () => ({
 }));
 
 const mockCreateRecord = vi.fn();
+const mockWaitForUpload = vi.fn();
 vi.mock("@/services/api", () => ({
   createRecord: (...args: unknown[]) => mockCreateRecord(...args),
+  waitForUpload: (...args: unknown[]) => mockWaitForUpload(...args),
 }));
 
 vi.mock("@/contracts/consentRegistry", () => ({
@@ -126,7 +128,12 @@ describe("useRecordUpload", () => {
         dataToEncryptHash: "hash123",
         evmContractConditions: [],
       });
-      mockCreateRecord.mockResolvedValue({ cid: "QmTestCid" });
+      mockCreateRecord.mockResolvedValue({ id: "job-1", status: "queued" });
+      mockWaitForUpload.mockResolvedValue({
+        id: "job-1",
+        status: "completed",
+        ipfs_cid: "QmTestCid",
+      });
       mockWriteContractAsync.mockResolvedValue("0xTxHash");
     });
 
@@ -198,6 +205,22 @@ describe("useRecordUpload", () => {
       });
     });
 
+    it("waits for the upload job after registering", async () => {
+      const { result } = renderHook(() => useRecordUpload());
+      const mockFile = new File(["test"], "test.pdf", {
+        type: "application/pdf",
+      });
+
+      await act(async () => {
+        await result.current.upload(mockFile, "Test Record", "0xPatient");
+      });
+
+      expect(mockWaitForUpload).toHaveBeenCalledWith("job-1");
+      expect(mockWriteContractAsync.mock.invocationCallOrder[0]).toBeLessThan(
+        mockWaitForUpload.mock.invocationCallOrder[0]
+      );
+    });
+
     it("returns CID on successful upload", async () => {
       const { result } = renderHook(() => useRecordUpload());
       const mockFile = new File(["test"], "test.pdf", {
@@ -277,7 +300,12 @@ describe("useRecordUpload", () => {
         dataToEncryptHash: "hash123",
         evmContractConditions: [],
       });
-      mockCreateRecord.mockResolvedValue({ cid: "QmTestCid" });
+      mockCreateRecord.mockResolvedValue({ id: "job-1", status: "queued" });
+      mockWaitForUpload.mockResolvedValue({
+        id: "job-1",
+        status: "completed",
+        ipfs_cid: "QmTestCid",
+      });
       mockWriteContractAsync.mockRejectedValue(
         new Error("Transaction rejected")
       );
@@ -298,6 +326,32 @@ describe("useRecordUpload", () => {
       expect(result.current.status).toBe("error");
     });
 
+    it("sets error status when the upload job fails", async () => {
+      mockEncryptFile.mockResolvedValue({
+        encryptedBlob: new Blob(["encrypted"]),
+        dataToEncryptHash: "hash123",
+        evmContractConditions: [],
+      });
+      mockCreateRecord.mockResolvedValue({ id: "job-1", status: "queued" });
+      mockWriteContractAsync.mockResolvedValue("0xTxHash");
+      mockWaitForUpload.mockRejectedValue(new Error("pinning failed"));
+
+      const { result } = renderHook(() => useRecordUpload());
+      const mockFile = new File(["test"], "test.pdf", {
+        type: "application/pdf",
+      });
+
+      await act(async () => {
+        try {
+          await result.current.upload(mockFile, "Test Record", "0xPatient");
+        } catch {
+          // Expected error
+        }
+      });
+
+      expect(result.current.status).toBe("error");
+    });
+
     it("throws the original error", async () => {
       const originalError = new Error("Original error");
       mockEncryptFile.mockRejectedValue(originalError);
@@ -322,7 +376,12 @@ describe("useRecordUpload", () => {
         dataToEncryptHash: "hash123",
         evmContractConditions: [],
       });
-      mockCreateRecord.mockResolvedValue({ cid: "QmTestCid" });
+      mockCreateRecord.mockResolvedValue({ id: "job-1", status: "queued" });
+      mockWaitForUpload.mockResolvedValue({
+        id: "job-1",
+        status: "completed",
+        ipfs_cid: "QmTestCid",
+      });
       mockWriteContractAsync.mockResolvedValue("0xTxHash");
 
       const { result } = renderHook(() => useRecordUpload());
@@ -4,7 +4,7 @@ import { useState } from "react";
 import { useWalletClient, useWriteContract, useConfig } from "wagmi";
 import { waitForTransactionReceipt } from "@wagmi/core";
 import { encryptFile } from "@/services/lit";
-import { createRecord } from "@/services/api";
+import { createRecord, waitForUpload } from "@/services/api";
 import {
   CONSENT_REGISTRY_ABI,
   CONSENT_REGISTRY_ADDRESS,
@@ -15,6 +15,7 @@ export type UploadStatus =
   | "encrypting"
   | "uploading"
   | "registering"
+  | "processing"
   | "success"
   | "error";
 
@@ -59,7 +60,7 @@ export function useRecordUpload(): UseRecordUploadReturn {
 
       // Step 3: Upload to backend with the same conditions used for encryption
       setStatus("uploading");
-      const result = await createRecord({
+      const job = await createRecord({
         recordId,
         name,
         patientAddress,
@@ -93,8 +94,12 @@ export function useRecordUpload(): UseRecordUploadReturn {
         status: receipt.status,
       });
 
+      // Step 5: Wait for the backend to pin the file and save the record
+      setStatus("processing");
+      const completed = await waitForUpload(job.id);
+
       setStatus("success");
-      return result.cid;
+      return completed.ipfs_cid ?? "";
     } catch (err) {
       setStatus("error");
       console.error("[useRecordUpload] Upload failed:", err);
@@ -3,6 +3,8 @@ import { setupServer } from "msw/node";
 import { http, HttpResponse } from "msw";
 import {
   createRecord,
+  getUploadJob,
+  waitForUpload,
   getEncryptedFile,
   getPatientRecords,
   getRecord,
@@ -41,6 +43,16 @@ const mockResearcherProfile = {
   wallet_address: "0x0987654321098765432109876543210987654321",
 };
 
+const mockUploadJob = {
+  id: "job-1",
+  record_id: "rec-1",
+  status: "queued",
+  attempts: 0,
+  content_size: 4,
+  created_at: "2025-12-15T10:30:00Z",
+  updated_at: "2025-12-15T10:30:00Z",
+};
+
 const server = setupServer();
 
 beforeAll(() => server.listen({ onUnhandledRequest: "error" }));
@@ -55,10 +67,7 @@ describe("API Service", () => {
           const formData = await request.formData();
           expect(formData.get("name")).toBe("Test Record");
           expect(formData.get("patient_address")).toBe("0x123");
-          return HttpResponse.json({
-            message: "Record created",
-            cid: "QmTest",
-          });
+          return HttpResponse.json(mockUploadJob, { status: 202 });
         })
       );
 
@@ -71,8 +80,8 @@ describe("API Service", () => {
         encryptedFile: new Blob(["test"]),
       });
 
-      expect(result.message).toBe("Record created");
-      expect(result.cid).toBe("QmTest");
+      expect(result.id).toBe("job-1");
+      expect(result.status).toBe("queued");
     });
 
     it("throws ApiError on failure", async () => {
@@ -95,6 +104,70 @@ describe("API Service", () => {
     });
   });
 
+  describe("getUploadJob", () => {
+    it("fetches the upload job", async () => {
+      server.use(
+        http.get(`${API_URL}/api/v1/uploads/job-1`, () => {
+          return HttpResponse.json(mockUploadJob);
+        })
+      );
+
+      const job = await getUploadJob("job-1");
+      expect(job.record_id).toBe("rec-1");
+    });
+  });
+
+  describe("waitForUpload", () => {
+    it("polls until the upload completes", async () => {
+      let calls = 0;
+      server.use(
+        http.get(`${API_URL}/api/v1/uploads/job-1`, () => {
+          calls++;
+          if (calls < 3) {
+            return HttpResponse.json({ ...mockUploadJob, status: "pinning" });
+          }
+          return HttpResponse.json({
+            ...mockUploadJob,
+            status: "completed",
+            ipfs_cid: "QmTest",
+          });
+        })
+      );
+
+      const job = await waitForUpload("job-1", { intervalMs: 1 });
+      expect(calls).toBe(3);
+      expect(job.ipfs_cid).toBe("QmTest");
+    });
+
+    it("rejects when the upload fails", async () => {
+      server.use(
+        http.get(`${API_URL}/api/v1/uploads/job-1`, () => {
+          return HttpResponse.json({
+            ...mockUploadJob,
+            status: "failed",
+            error: "content verification failed",
+          });
+        })
+      );
+
+      await expect(waitForUpload("job-1", { intervalMs: 1 })).rejects.toThrow(
+        "content verification failed"
+      );
+    });
+
+    it("rejects when the upload does not finish in time", async () => {
+      server.use(
+        http.get(`${API_URL}/api/v1/uploads/job-1`, () => {
+          return HttpResponse.json(mockUploadJob);
+        })
+      );
+
+      await expect(
+        waitForUpload("job-1", { intervalMs: 1, timeoutMs: 0 })
+      ).rejects.toThrow("Upload did not finish in time");
+    });
+  });
+
   describe("getEncryptedFile", () => {
     it("fetches encrypted file from IPFS", async () => {
       const testContent = new Uint8Array([1, 2, 3, 4]);
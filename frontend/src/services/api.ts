@@ -38,14 +38,31 @@ export interface CreateRecordRequest {
   encryptedFile: Blob;
 }
 
-export interface CreateRecordResponse {
-  message: string;
-  cid: string;
+export type UploadJobStatus =
+  | "queued"
+  | "pinning"
+  | "saving"
+  | "completed"
+  | "failed";
+
+export interface UploadJob {
+  id: string;
+  record_id: string;
+  status: UploadJobStatus;
+  attempts: number;
+  content_size: number;
+  ipfs_cid?: string;
+  content_sha256?: string;
+  error?: string;
+  created_at: string;
+  updated_at: string;
+  completed_at?: string;
 }
 
+// createRecord queues the upload; the record is saved once the job completes.
 export async function createRecord(
   request: CreateRecordRequest
-): Promise<CreateRecordResponse> {
+): Promise<UploadJob> {
   const formData = new FormData();
   formData.append("record_id", request.recordId);
   formData.append("name", request.name);
@@ -59,7 +76,37 @@ export async function createRecord(
     body: formData,
   });
 
-  return handleResponse<CreateRecordResponse>(response);
+  return handleResponse<UploadJob>(response);
+}
+
+export async function getUploadJob(jobId: string): Promise<UploadJob> {
+  const response = await fetch(`${API_URL}/api/v1/uploads/${jobId}`);
+  return handleResponse<UploadJob>(response);
+}
+
+const UPLOAD_POLL_INTERVAL_MS = 2000;
+const UPLOAD_TIMEOUT_MS = 15 * 60 * 1000;
+
+// waitForUpload polls an upload job until it completes, and rejects when it
+// fails or does not finish within the timeout.
+export async function waitForUpload(
+  jobId: string,
+  { intervalMs = UPLOAD_POLL_INTERVAL_MS, timeoutMs = UPLOAD_TIMEOUT_MS } = {}
+): Promise<UploadJob> {
+  const deadline = Date.now() + timeoutMs;
+  for (;;) {
+    const job = await getUploadJob(jobId);
+    if (job.status === "completed") {
+      return job;
+    }
+    if (job.status === "failed") {
+      throw new Error(job.error || "Upload failed");
+    }
+    if (Date.now() >= deadline) {
+      throw new Error("Upload did not finish in time");
+    }
+    await new Promise((resolve) => setTimeout(resolve, intervalMs));
+  }
 }
 
 export async function getEncryptedFile(cid: string): Promise<Blob> {
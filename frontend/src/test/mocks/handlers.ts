@@ -58,11 +58,32 @@ export const handlers = [
   }),
 
   http.post(`${API_BASE}/api/v1/records`, async ({ request }) => {
-    const body = await request.json();
+    const formData = await request.formData();
+    return HttpResponse.json(
+      {
+        id: "new-upload-id",
+        record_id: formData.get("record_id"),
+        status: "queued",
+        attempts: 0,
+        content_size: 0,
+        created_at: "2025-12-15T10:30:00Z",
+        updated_at: "2025-12-15T10:30:00Z",
+      },
+      { status: 202 }
+    );
+  }),
+
+  http.get(`${API_BASE}/api/v1/uploads/:jobId`, ({ params }) => {
     return HttpResponse.json({
-      ...mockPatientRecord,
-      ...(body as object),
-      id: "new-record-id",
+      id: params.jobId,
+      record_id: "new-record-id",
+      status: "completed",
+      attempts: 1,
+      content_size: 0,
+      ipfs_cid: mockPatientRecord.ipfs_cid,
+      created_at: "2025-12-15T10:30:00Z",
+      updated_at: "2025-12-15T10:30:00Z",
+      completed_at: "2025-12-15T10:30:00Z",
     });
   }),
 